@@ -0,0 +1,46 @@
+package api
+
+import (
+	"designs.capital/dogepool/rpc"
+)
+
+type Node struct {
+	Name                 string
+	Active               bool
+	Healthy              bool
+	Reachable            bool
+	Synced               bool
+	InitialBlockDownload bool
+	Peers                int64
+	Height               int64
+	TipAgeSeconds        int64
+	Warnings             string
+	WalletAvailable      bool
+	Problems             []string
+	Checked              string
+}
+
+// Node URLs are left out, only names are exposed
+func getNodesHealth(managers map[string]*rpc.Manager) map[string][]Node {
+	nodes := make(map[string][]Node, len(managers))
+	for chain, manager := range managers {
+		for _, health := range manager.Health() {
+			nodes[chain] = append(nodes[chain], Node{
+				Name:                 health.Name,
+				Active:               health.Active,
+				Healthy:              health.Healthy,
+				Reachable:            health.Reachable,
+				Synced:               health.Synced,
+				InitialBlockDownload: health.InitialBlockDownload,
+				Peers:                health.Peers,
+				Height:               health.Height,
+				TipAgeSeconds:        int64(health.TipAge.Seconds()),
+				Warnings:             health.Warnings,
+				WalletAvailable:      health.WalletAvailable,
+				Problems:             health.Problems,
+				Checked:              health.Checked.Format(JavascriptISOFormat),
+			})
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"designs.capital/dogepool/persistence"
+)
+
+const minerPaymentsPageSize = 50
+
+type Payment struct {
+	Chain         string
+	Address       string
+	Amount        float64
+	TransactionID string
+	Status        string // broadcast, confirmed, or conflicted and dropped when paid again
+	Confirmations int64
+	Created       string
+}
+
+// A merged mining login holds one address per chain, each paid separately
+func getMinerPayments(poolId, minerId string) []Payment {
+	var payments []Payment
+	if minerId == "" {
+		return payments
+	}
+
+	var found []persistence.Payment
+	for _, address := range strings.Split(minerId, "-") {
+		page, err := persistence.Payments.PagePayments(poolId, address, 0, minerPaymentsPageSize)
+		logOnError(err)
+		found = append(found, page...)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Created.After(found[j].Created)
+	})
+
+	for _, payment := range found {
+		payments = append(payments, Payment{
+			Chain:         payment.Chain,
+			Address:       payment.Address,
+			Amount:        payment.Amount,
+			TransactionID: payment.TransactionConfirmationData,
+			Status:        payment.Status,
+			Confirmations: payment.Confirmations,
+			Created:       payment.Created.Format(JavascriptISOFormat),
+		})
+	}
+	return payments
+}
@@ -2,11 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
 	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/rpc"
 )
 
 const JavascriptISOFormat = "2006-01-02T15:04:05.999Z07:00"
@@ -40,6 +42,61 @@ func minerHistory(response http.ResponseWriter, request *http.Request) {
 	}
 }
 
+func minerPayments(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		http.Error(response, fmt.Sprintf("method %s is not allowed", request.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	minerId := request.URL.Query().Get("id")
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+	err := json.NewEncoder(response).Encode(getMinerPayments(serverConfig.PoolName, minerId))
+	if err != nil {
+		http.Error(response, fmt.Sprintf("error building the response, %v", err), http.StatusInternalServerError)
+	}
+}
+
+// GET reads a miner's payment threshold for a chain, POST changes it with a signed message
+func minerSettings(response http.ResponseWriter, request *http.Request) {
+	var settings MinerSettings
+	var err error
+	switch request.Method {
+	case http.MethodGet:
+		query := request.URL.Query()
+		settings, err = getMinerSettings(serverConfig, query.Get("id"), query.Get("chain"))
+	case http.MethodPost:
+		var update updateSettingsRequest
+		err = json.NewDecoder(request.Body).Decode(&update)
+		if err != nil {
+			http.Error(response, fmt.Sprintf("invalid request, %v", err), http.StatusBadRequest)
+			return
+		}
+		settings, err = updateMinerSettings(request.Context(), serverConfig, rpcManagers, update)
+	default:
+		http.Error(response, fmt.Sprintf("method %s is not allowed", request.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+	var settingsErr settingsError
+	if errors.As(err, &settingsErr) {
+		http.Error(response, settingsErr.Error(), settingsErr.status)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(response, "can't load or save the miner settings", http.StatusInternalServerError)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(response).Encode(settings)
+	if err != nil {
+		http.Error(response, fmt.Sprintf("error building the response, %v", err), http.StatusInternalServerError)
+	}
+}
+
 func poolIndex(response http.ResponseWriter, request *http.Request) {
 	if request.Method != http.MethodGet {
 		http.Error(response, fmt.Sprintf("method %s is not allowed", request.Method), http.StatusMethodNotAllowed)
@@ -54,14 +111,33 @@ func poolIndex(response http.ResponseWriter, request *http.Request) {
 	}
 }
 
+func nodesIndex(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		http.Error(response, fmt.Sprintf("method %s is not allowed", request.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+	err := json.NewEncoder(response).Encode(getNodesHealth(rpcManagers))
+	if err != nil {
+		http.Error(response, fmt.Sprintf("error building the response, %v", err), http.StatusInternalServerError)
+	}
+}
+
 var serverConfig *config.Config
+var rpcManagers map[string]*rpc.Manager
 
-func ListenAndServe(configuration *config.Config) {
+func ListenAndServe(configuration *config.Config, managers map[string]*rpc.Manager) {
 	serverConfig = configuration
+	rpcManagers = managers
 
 	http.HandleFunc("/miner", minerIndex)
 	http.HandleFunc("/miner-history", minerHistory)
+	http.HandleFunc("/miner-payments", minerPayments)
+	http.HandleFunc("/miner-settings", minerSettings)
 	http.HandleFunc("/pool", poolIndex)
+	http.HandleFunc("/nodes", nodesIndex)
 
 	log.Fatal(http.ListenAndServe(":"+configuration.API.Port, nil))
 }
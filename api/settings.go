@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+)
+
+// How far the signed timestamp may be from now, so an old signature can't be replayed later
+const settingsSignatureWindow = 10 * time.Minute
+
+type MinerSettings struct {
+	Miner            string
+	Chain            string
+	PaymentThreshold float32 // The miner's own, 0 when unset
+	PoolMinimum      float32
+}
+
+// PaymentThreshold is a string so the signed message is exactly what was sent
+type updateSettingsRequest struct {
+	Miner            string
+	Chain            string
+	PaymentThreshold string
+	Timestamp        int64
+	Signature        string
+}
+
+// Wraps errors that are the caller's fault
+type settingsError struct {
+	status int
+	error
+}
+
+// The message a miner signs with their chain's payout address
+func settingsMessage(poolID string, request updateSettingsRequest) string {
+	m := "%v: set %v payment threshold of %v to %v at %v"
+	return fmt.Sprintf(m, poolID, request.Chain, request.Miner, request.PaymentThreshold, request.Timestamp)
+}
+
+func getMinerSettings(configuration *config.Config, minerId, chain string) (MinerSettings, error) {
+	payoutConfig, exists := configuration.Payouts.Chains[chain]
+	if !exists {
+		return MinerSettings{}, settingsError{http.StatusBadRequest, errors.New("unknown chain: " + chain)}
+	}
+
+	settings := MinerSettings{
+		Miner:       minerId,
+		Chain:       chain,
+		PoolMinimum: payoutConfig.MinerMinimumPayment,
+	}
+	stored, err := persistence.Miners.GetSettings(configuration.PoolName, minerId, chain)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	settings.PaymentThreshold = stored.PaymentThreshold
+	return settings, nil
+}
+
+func updateMinerSettings(ctx context.Context, configuration *config.Config, managers map[string]*rpc.Manager, request updateSettingsRequest) (MinerSettings, error) {
+	payoutConfig, exists := configuration.Payouts.Chains[request.Chain]
+	if !exists {
+		return MinerSettings{}, settingsError{http.StatusBadRequest, errors.New("unknown chain: " + request.Chain)}
+	}
+	manager, exists := managers[request.Chain]
+	if !exists {
+		return MinerSettings{}, errors.New("no node for chain: " + request.Chain)
+	}
+
+	threshold, err := strconv.ParseFloat(request.PaymentThreshold, 32)
+	if err != nil {
+		return MinerSettings{}, settingsError{http.StatusBadRequest, errors.New("invalid payment threshold")}
+	}
+	if float32(threshold) < payoutConfig.MinerMinimumPayment {
+		m := "payment threshold can't be below the pool minimum of %v"
+		return MinerSettings{}, settingsError{http.StatusBadRequest, fmt.Errorf(m, payoutConfig.MinerMinimumPayment)}
+	}
+
+	signed := time.Unix(request.Timestamp, 0)
+	if time.Since(signed).Abs() > settingsSignatureWindow {
+		return MinerSettings{}, settingsError{http.StatusBadRequest, errors.New("signature timestamp is too old or in the future")}
+	}
+
+	address, err := chainAddress(configuration, request.Miner, request.Chain)
+	if err != nil {
+		return MinerSettings{}, settingsError{http.StatusBadRequest, err}
+	}
+
+	message := settingsMessage(configuration.PoolName, request)
+	verified, err := manager.GetActiveClient().VerifyMessage(ctx, address, request.Signature, message)
+	var rpcError *rpc.Error
+	if errors.As(err, &rpcError) || (err == nil && !verified) {
+		// Malformed signatures and addresses that can't sign are refused by the node too
+		return MinerSettings{}, settingsError{http.StatusForbidden, errors.New("signature doesn't match " + address)}
+	}
+	if err != nil {
+		return MinerSettings{}, err
+	}
+
+	err = persistence.Miners.UpdateSettings(persistence.MinerSettings{
+		PoolID:           configuration.PoolName,
+		Miner:            request.Miner,
+		Chain:            request.Chain,
+		PaymentThreshold: float32(threshold),
+	})
+	if err != nil {
+		return MinerSettings{}, err
+	}
+
+	return MinerSettings{
+		Miner:            request.Miner,
+		Chain:            request.Chain,
+		PaymentThreshold: float32(threshold),
+		PoolMinimum:      payoutConfig.MinerMinimumPayment,
+	}, nil
+}
+
+// The chain's address in a merged mining login, the same one payouts pay to
+func chainAddress(configuration *config.Config, minerId, chain string) (string, error) {
+	addresses := strings.Split(minerId, "-")
+	if len(addresses) == 1 {
+		return addresses[0], nil
+	}
+	for i, name := range configuration.BlockChainOrder {
+		if name == chain && i < len(addresses) {
+			return addresses[i], nil
+		}
+	}
+	return "", errors.New("no " + chain + " address in miner " + minerId)
+}
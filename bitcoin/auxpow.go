@@ -1,6 +1,11 @@
 package bitcoin
 
-import "fmt"
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
 
 const (
 	mergedMiningHeader  = "fabe6d6d"
@@ -18,86 +23,116 @@ type AuxBlock struct {
 	Target            string `json:"target"`
 }
 
+// Namecoin derived daemons return the target as "_target"
+func (b *AuxBlock) UnmarshalJSON(data []byte) error {
+	type auxBlock AuxBlock
+	aux := struct {
+		*auxBlock
+		UnderscoredTarget string `json:"_target"`
+	}{auxBlock: (*auxBlock)(b)}
+
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+
+	if b.Target == "" {
+		b.Target = aux.UnderscoredTarget
+	}
+
+	return nil
+}
+
 func (b *AuxBlock) GetWork() string {
 	return mergedMiningHeader + b.Hash + mergedMiningTrailer
 }
 
 type AuxPow struct {
-	ParentCoinbase   string
-	ParentHeaderHash string
+	ParentCoinbase   []byte
+	ParentHeaderHash [32]byte
 	ParentMerkleBranch
 	auxMerkleBranch      AuxMerkleBranch
-	ParentHeaderUnhashed string
+	ParentHeaderUnhashed []byte
 }
 
 func MakeAuxPow(parentBlock BitcoinBlock) AuxPow {
-	if parentBlock.Hash == "" {
-		panic("Set parent block hash first")
+	if !parentBlock.headerMade {
+		panic("Make parent block header first")
 	}
 
 	return AuxPow{
-		ParentCoinbase:       parentBlock.Coinbase,
-		ParentHeaderHash:     parentBlock.Hash,
-		ParentMerkleBranch:   makeParentMerkleBranch(parentBlock.MerkleSteps),
+		ParentCoinbase:       parentBlock.coinbase,
+		ParentHeaderHash:     doubleSha256Bytes(parentBlock.header[:]),
+		ParentMerkleBranch:   makeParentMerkleBranch(parentBlock.merkleBranch),
 		auxMerkleBranch:      makeAuxChainMerkleBranch(),
-		ParentHeaderUnhashed: parentBlock.Header,
+		ParentHeaderUnhashed: parentBlock.header[:],
 	}
 }
 
 func (p *AuxPow) Serialize() string {
-	return p.ParentCoinbase +
-		p.ParentHeaderHash +
-		p.ParentMerkleBranch.Serialize() +
-		p.auxMerkleBranch.Serialize() +
-		p.ParentHeaderUnhashed
+	serialized := append([]byte{}, p.ParentCoinbase...)
+	serialized = append(serialized, p.ParentHeaderHash[:]...)
+	serialized = p.ParentMerkleBranch.appendSerialized(serialized)
+	serialized = p.auxMerkleBranch.appendSerialized(serialized)
+	serialized = append(serialized, p.ParentHeaderUnhashed...)
+	return hex.EncodeToString(serialized)
 }
 
 type ParentMerkleBranch struct {
 	Length uint
-	Items  []string
-	mask   string
+	Items  [][32]byte
+	mask   uint32
 }
 
-func makeParentMerkleBranch(items []string) ParentMerkleBranch {
+func makeParentMerkleBranch(items [][32]byte) ParentMerkleBranch {
 	length := uint(len(items))
 	return ParentMerkleBranch{
 		Length: length,
 		Items:  items,
-		mask:   "00000000",
+		mask:   0,
 	}
 }
 
-func (pm *ParentMerkleBranch) Serialize() string {
-	items := ""
+func (pm *ParentMerkleBranch) appendSerialized(buffer []byte) []byte {
+	buffer = appendVarUint(buffer, uint64(pm.Length))
 	for _, item := range pm.Items {
-		items = items + item
+		buffer = append(buffer, item[:]...)
 	}
-	return varUint(pm.Length) + items + pm.mask
+	return binary.LittleEndian.AppendUint32(buffer, pm.mask)
+}
+
+func (pm *ParentMerkleBranch) Serialize() string {
+	return hex.EncodeToString(pm.appendSerialized(nil))
 }
 
 type AuxMerkleBranch struct {
-	numberOfBranches string
-	mask             string
+	numberOfBranches uint
+	mask             uint32
 }
 
 func makeAuxChainMerkleBranch() AuxMerkleBranch {
 	return AuxMerkleBranch{
-		numberOfBranches: "00",
-		mask:             "00000000",
+		numberOfBranches: 0,
+		mask:             0,
 	}
 }
 
+func (am *AuxMerkleBranch) appendSerialized(buffer []byte) []byte {
+	buffer = appendVarUint(buffer, uint64(am.numberOfBranches))
+	return binary.LittleEndian.AppendUint32(buffer, am.mask)
+}
+
 func (am *AuxMerkleBranch) Serialize() string {
-	return am.numberOfBranches + am.mask
+	return hex.EncodeToString(am.appendSerialized(nil))
 }
 
 func debugAuxPow(parentBlock BitcoinBlock, parentMerkle ParentMerkleBranch, auxchainMerkle AuxMerkleBranch) {
 	fmt.Println()
-	fmt.Println("coinbase", parentBlock.Coinbase)
-	fmt.Println("hash", parentBlock.Hash)
+	fmt.Println("coinbase", hex.EncodeToString(parentBlock.coinbase))
+	fmt.Println("hash", hex.EncodeToString(parentBlock.hash[:]))
 	fmt.Println("merkleSteps", parentBlock.MerkleSteps)
 	fmt.Println("merkleDigested", parentMerkle.Serialize())
 	fmt.Println("chainmerklebranch", auxchainMerkle.Serialize())
-	fmt.Println("header", parentBlock.Header)
+	fmt.Println("header", parentBlock.HeaderHex())
 	fmt.Println()
 }
@@ -5,15 +5,27 @@ import (
 )
 
 type BitcoinBlock struct {
-	Template             *Template
+	Template *Template
+	Chain    Blockchain
+
+	// Stratum work, hex encoded once per job
 	ReversePrevBlockHash string
 	CoinbaseInitial      string
 	CoinbaseFinal        string
 	MerkleSteps          []string
-	Coinbase             string
-	Header               string
-	Hash                 string
-	Chain                Blockchain
+
+	// Shared, read only, by every share of a job
+	coinbasePrefix []byte
+	coinbaseSuffix []byte
+	merkleBranch   [][32]byte
+	payouts        []CoinbaseOutput
+
+	// Per share, copy the job block before making a header
+	header     blockHeader
+	coinbase   []byte
+	hash       [32]byte // Proof of work digest, big endian
+	headerMade bool
+	hashed     bool
 }
 
 func (b BitcoinBlock) ChainName() string {
@@ -23,6 +35,26 @@ func (b BitcoinBlock) ChainName() string {
 	return b.Chain.ChainName()
 }
 
+// The coinbase pays miners directly
+func (b BitcoinBlock) PaysOutInCoinbase() bool {
+	for _, payout := range b.payouts {
+		if !payout.PoolReward {
+			return true
+		}
+	}
+	return false
+}
+
+// The coinbase pays pool reward recipients other than the pool itself
+func (b BitcoinBlock) PaysPoolRewardsInCoinbase() bool {
+	for _, payout := range b.payouts {
+		if payout.PoolReward {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *BitcoinBlock) init(chain Blockchain) {
 	if chain == nil {
 		panic("Chain cannot be null")
@@ -31,10 +63,10 @@ func (b *BitcoinBlock) init(chain Blockchain) {
 }
 
 func (b *BitcoinBlock) ToHex() string {
-    submission, err := b.Submit()
-    if err != nil {
-        log.Printf("Error converting block to hex: %v", err)
-        return ""
-    }
-    return submission
+	submission, err := b.Submit()
+	if err != nil {
+		log.Printf("Error converting block to hex: %v", err)
+		return ""
+	}
+	return submission
 }
@@ -0,0 +1,41 @@
+package bitcoin
+
+import (
+	"regexp"
+)
+
+type BitcoinCash struct{}
+
+func (BitcoinCash) ChainName() string {
+	return "bitcoincash"
+}
+
+func (BitcoinCash) Algorithm() string {
+	return AlgorithmSha256d
+}
+
+func (BitcoinCash) CoinbaseDigest(coinbase []byte) ([]byte, error) {
+	return DoubleSha256(coinbase)
+}
+
+func (BitcoinCash) HeaderDigest(header []byte) ([]byte, error) {
+	return DoubleSha256(header)
+}
+
+// Stratum difficulty 1 is the SHA256d difficulty 1 target
+func (BitcoinCash) ShareMultiplier() float64 {
+	return 1
+}
+
+// Cashaddr, with or without its prefix, or legacy base58
+func (BitcoinCash) ValidMainnetAddress(address string) bool {
+	return regexp.MustCompile("^(bitcoincash:)?(q|p)[02-9ac-hj-np-z]{41}$|^(1|3)[a-km-zA-HJ-NP-Z1-9]{25,34}$").MatchString(address)
+}
+
+func (BitcoinCash) ValidTestnetAddress(address string) bool {
+	return regexp.MustCompile("^(bchtest:|bchreg:)?(q|p)[02-9ac-hj-np-z]{41}$|^(m|n|2)[a-km-zA-HJ-NP-Z1-9]{25,34}$").MatchString(address)
+}
+
+func (BitcoinCash) MinimumConfirmations() uint {
+	return uint(BitcoinMinConfirmations)
+}
@@ -0,0 +1,114 @@
+package bitcoin
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// BLAKE3, as MWEB hashes its headers.  Only single chunk inputs are needed,
+// an MWEB header is a few hundred bytes at most.
+// https://github.com/BLAKE3-team/BLAKE3-specs/blob/master/blake3.pdf
+
+const (
+	blake3BlockLength = 64
+	blake3ChunkLength = 1024
+
+	blake3ChunkStart = 1 << 0
+	blake3ChunkEnd   = 1 << 1
+	blake3Root       = 1 << 3
+)
+
+var blake3IV = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+var blake3MessagePermutation = [16]int{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8}
+
+func blake3Sum256(input []byte) ([32]byte, error) {
+	var digest [32]byte
+	if len(input) > blake3ChunkLength {
+		return digest, errors.New("blake3: only single chunk inputs are supported")
+	}
+
+	chainingValue := blake3IV
+	blocks := (len(input) + blake3BlockLength - 1) / blake3BlockLength
+	if blocks == 0 {
+		blocks = 1
+	}
+
+	for i := 0; i < blocks; i++ {
+		var block [blake3BlockLength]byte
+		blockLength := copy(block[:], input[i*blake3BlockLength:])
+
+		var words [16]uint32
+		for j := range words {
+			words[j] = binary.LittleEndian.Uint32(block[j*4:])
+		}
+
+		flags := uint32(0)
+		if i == 0 {
+			flags |= blake3ChunkStart
+		}
+		if i == blocks-1 {
+			flags |= blake3ChunkEnd | blake3Root
+		}
+
+		state := blake3Compress(chainingValue, words, uint32(blockLength), flags)
+		copy(chainingValue[:], state[:8])
+	}
+
+	for i, word := range chainingValue {
+		binary.LittleEndian.PutUint32(digest[i*4:], word)
+	}
+	return digest, nil
+}
+
+// The chunk counter is always 0 for a single chunk
+func blake3Compress(chainingValue [8]uint32, message [16]uint32, blockLength, flags uint32) [16]uint32 {
+	state := [16]uint32{
+		chainingValue[0], chainingValue[1], chainingValue[2], chainingValue[3],
+		chainingValue[4], chainingValue[5], chainingValue[6], chainingValue[7],
+		blake3IV[0], blake3IV[1], blake3IV[2], blake3IV[3],
+		0, 0, blockLength, flags,
+	}
+
+	for round := 0; round < 7; round++ {
+		blake3Round(&state, &message)
+		if round < 6 {
+			var permuted [16]uint32
+			for i, from := range blake3MessagePermutation {
+				permuted[i] = message[from]
+			}
+			message = permuted
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		state[i] ^= state[i+8]
+		state[i+8] ^= chainingValue[i]
+	}
+	return state
+}
+
+func blake3Round(s *[16]uint32, m *[16]uint32) {
+	blake3G(s, 0, 4, 8, 12, m[0], m[1])
+	blake3G(s, 1, 5, 9, 13, m[2], m[3])
+	blake3G(s, 2, 6, 10, 14, m[4], m[5])
+	blake3G(s, 3, 7, 11, 15, m[6], m[7])
+	blake3G(s, 0, 5, 10, 15, m[8], m[9])
+	blake3G(s, 1, 6, 11, 12, m[10], m[11])
+	blake3G(s, 2, 7, 8, 13, m[12], m[13])
+	blake3G(s, 3, 4, 9, 14, m[14], m[15])
+}
+
+func blake3G(s *[16]uint32, a, b, c, d int, x, y uint32) {
+	s[a] = s[a] + s[b] + x
+	s[d] = bits.RotateLeft32(s[d]^s[a], -16)
+	s[c] = s[c] + s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -12)
+	s[a] = s[a] + s[b] + y
+	s[d] = bits.RotateLeft32(s[d]^s[a], -8)
+	s[c] = s[c] + s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -7)
+}
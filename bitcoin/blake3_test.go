@@ -0,0 +1,61 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// From the BLAKE3 team's test_vectors.json, the hash mode's first 32 bytes.
+// Inputs are the repeating byte sequence 0, 1, ... 250.
+// https://github.com/BLAKE3-team/BLAKE3/blob/master/test_vectors/test_vectors.json
+var blake3Vectors = []struct {
+	length int
+	hash   string
+}{
+	{0, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+	{1, "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+	{2, "7b7015bb92cf0b318037702a6cdd81dee41224f734684c2c122cd6359cb1ee63"},
+	{3, "e1be4d7a8ab5560aa4199eea339849ba8e293d55ca0a81006726d184519e647f"},
+	{4, "f30f5ab28fe047904037f77b6da4fea1e27241c5d132638d8bedce9d40494f32"},
+	{5, "b40b44dfd97e7a84a996a91af8b85188c66c126940ba7aad2e7ae6b385402aa2"},
+	{6, "06c4e8ffb6872fad96f9aaca5eee1553eb62aed0ad7198cef42e87f6a616c844"},
+	{7, "3f8770f387faad08faa9d8414e9f449ac68e6ff0417f673f602a646a891419fe"},
+	{8, "2351207d04fc16ade43ccab08600939c7c1fa70a5c0aaca76063d04c3228eaeb"},
+	{63, "e9bc37a594daad83be9470df7f7b3798297c3d834ce80ba85d6e207627b7db7b"},
+	{64, "4eed7141ea4a5cd4b788606bd23f46e212af9cacebacdc7d1f4c6dc7f2511b98"},
+	{65, "de1e5fa0be70df6d2be8fffd0e99ceaa8eb6e8c93a63f2d8d1c30ecb6b263dee"},
+	{127, "d81293fda863f008c09e92fc382a81f5a0b4a1251cba1634016a0f86a6bd640d"},
+	{128, "f17e570564b26578c33bb7f44643f539624b05df1a76c81f30acd548c44b45ef"},
+	{129, "683aaae9f3c5ba37eaaf072aed0f9e30bac0865137bae68b1fde4ca2aebdcb12"},
+	{1023, "10108970eeda3eb932baac1428c7a2163b0e924c9a9e25b35bba72b28f70bd11"},
+	{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+}
+
+func blake3Input(length int) []byte {
+	input := make([]byte, length)
+	for i := range input {
+		input[i] = byte(i % 251)
+	}
+	return input
+}
+
+func TestBlake3Vectors(t *testing.T) {
+	for _, vector := range blake3Vectors {
+		digest, err := blake3Sum256(blake3Input(vector.length))
+		if err != nil {
+			t.Fatalf("%v bytes: %v", vector.length, err)
+		}
+		if hex.EncodeToString(digest[:]) != vector.hash {
+			t.Errorf("%v bytes: got %x", vector.length, digest)
+		}
+	}
+}
+
+// Past one chunk the tree hashing isn't implemented, that must be an error rather than a wrong hash
+func TestBlake3RejectsMultipleChunks(t *testing.T) {
+	for _, length := range []int{1025, 2048, 2049} {
+		if _, err := blake3Sum256(blake3Input(length)); err == nil {
+			t.Errorf("%v bytes: expected an error", length)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package bitcoin
+
+import (
+	"regexp"
+)
+
+type Bitcoin struct{}
+
+func (Bitcoin) ChainName() string {
+	return "bitcoin"
+}
+
+func (Bitcoin) Algorithm() string {
+	return AlgorithmSha256d
+}
+
+func (Bitcoin) CoinbaseDigest(coinbase []byte) ([]byte, error) {
+	return DoubleSha256(coinbase)
+}
+
+func (Bitcoin) HeaderDigest(header []byte) ([]byte, error) {
+	return DoubleSha256(header)
+}
+
+// Stratum difficulty 1 is the SHA256d difficulty 1 target
+func (Bitcoin) ShareMultiplier() float64 {
+	return 1
+}
+
+func (Bitcoin) ValidMainnetAddress(address string) bool {
+	return regexp.MustCompile("^(1|3)[a-km-zA-HJ-NP-Z1-9]{25,34}$|^bc1[02-9ac-hj-np-z]{11,71}$").MatchString(address)
+}
+
+func (Bitcoin) ValidTestnetAddress(address string) bool {
+	return regexp.MustCompile("^(m|n|2)[a-km-zA-HJ-NP-Z1-9]{25,34}$|^(tb1|bcrt1)[02-9ac-hj-np-z]{11,71}$").MatchString(address)
+}
+
+func (Bitcoin) MinimumConfirmations() uint {
+	return uint(BitcoinMinConfirmations)
+}
+
+func (Bitcoin) RetargetInterval() uint {
+	return 2016
+}
+
+func (Bitcoin) HalvingInterval() uint {
+	return 210000
+}
+
+func (Bitcoin) InitialSubsidy() uint {
+	return 50 * 100000000
+}
@@ -2,10 +2,16 @@ package bitcoin
 
 const BitcoinMinConfirmations = 102
 
+const (
+	AlgorithmScrypt  = "scrypt"
+	AlgorithmSha256d = "sha256d"
+)
+
 type Blockchain interface {
 	ChainName() string
-	CoinbaseDigest(coinbase string) (string, error)
-	HeaderDigest(header string) (string, error)
+	Algorithm() string // Aux chains must share their parent's proof of work
+	CoinbaseDigest(coinbase []byte) ([]byte, error)
+	HeaderDigest(header []byte) ([]byte, error)
 	ShareMultiplier() float64
 	MinimumConfirmations() uint
 
@@ -13,6 +19,41 @@ type Blockchain interface {
 	ValidTestnetAddress(address string) bool
 }
 
+// Chains whose difficulty and subsidy only change at fixed heights,
+// the only ones an empty job can be built for without a template
+type ScheduledChain interface {
+	Blockchain
+	RetargetInterval() uint
+	HalvingInterval() uint
+	InitialSubsidy() uint // Satoshis
+}
+
+// Chains with more than one proof of work select theirs through
+// the header version and a getblocktemplate argument
+type MultiAlgorithmChain interface {
+	Blockchain
+	TemplateAlgorithm() string
+	AlgorithmVersion(version uint32) uint32
+}
+
+const (
+	UnsignedFormatPSBT = "psbt"
+	UnsignedFormatRaw  = "raw"
+)
+
+// Chains whose wallets predate PSBT (BIP174) build raw unsigned transactions for offline signing
+type LegacyWalletChain interface {
+	Blockchain
+	RawUnsignedTransactions()
+}
+
+func UnsignedTransactionFormat(chainName string) string {
+	if _, ok := GetChain(chainName).(LegacyWalletChain); ok {
+		return UnsignedFormatRaw
+	}
+	return UnsignedFormatPSBT
+}
+
 func GetChain(chainName string) Blockchain {
 	switch chainName {
 	case "dogecoin":
@@ -21,6 +62,18 @@ func GetChain(chainName string) Blockchain {
 		return Litecoin{}
 	case "digibyte":
 		return Digibyte{}
+	case "digibyte-sha256d":
+		return Digibyte{algorithm: AlgorithmSha256d}
+	case "digibyte-scrypt":
+		return Digibyte{algorithm: AlgorithmScrypt}
+	case "bitcoin":
+		return Bitcoin{}
+	case "bitcoincash":
+		return BitcoinCash{}
+	case "namecoin":
+		return Namecoin{}
+	case "syscoin":
+		return Syscoin{}
 	default:
 		panic("Unknown blockchain: " + chainName)
 	}
@@ -1,6 +1,7 @@
 package bitcoin
 
 import (
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"log"
@@ -9,19 +10,16 @@ import (
 // https://developer.bitcoin.org/reference/transactions.html#coinbase-input-the-input-of-the-first-transaction-in-a-block
 
 type CoinbaseInital struct {
-	Version                     string
-	NumberOfInputs              string
-	PreviousOutputTransactionID string
-	PreviousOutputIndex         string
+	Version                     uint32
+	NumberOfInputs              uint
+	PreviousOutputTransactionID [32]byte
+	PreviousOutputIndex         uint32
 	BytesInArbitrary            uint
-	BytesInHeight               uint
-	HeightHex                   string
+	Height                      []byte
 }
 
 func (t *Template) CoinbaseInitial(arbitraryByteLength uint) CoinbaseInital {
-	heightBytes := eightLittleEndianBytes(t.Height)
-	heightBytes = removeInsignificantBytesLittleEndian(heightBytes)
-	heightHex := hex.EncodeToString(heightBytes)
+	heightBytes := scriptNumber(uint64(t.Height))
 
 	heightByteLen := uint(len(heightBytes))
 	arbitraryByteLength = arbitraryByteLength + heightByteLen + 1 // 1 is for the heightByteLen byte
@@ -31,96 +29,91 @@ func (t *Template) CoinbaseInitial(arbitraryByteLength uint) CoinbaseInital {
 	}
 
 	return CoinbaseInital{
-		Version:                     "01000000", // Different from template version
-		NumberOfInputs:              "01",
-		PreviousOutputTransactionID: "0000000000000000000000000000000000000000000000000000000000000000",
-		PreviousOutputIndex:         "ffffffff",
-		BytesInArbitrary:            arbitraryByteLength,
-		BytesInHeight:               heightByteLen,
-		HeightHex:                   heightHex,
+		Version:             1, // Different from template version
+		NumberOfInputs:      1,
+		PreviousOutputIndex: 0xffffffff,
+		BytesInArbitrary:    arbitraryByteLength,
+		Height:              heightBytes,
 	}
 }
 
-func (i CoinbaseInital) Serialize() string {
+func (i CoinbaseInital) Serialize() []byte {
 	// debugCoinbaseInitialOutput(i)
-	return i.Version +
-		i.NumberOfInputs +
-		i.PreviousOutputTransactionID +
-		i.PreviousOutputIndex +
-		varUint(i.BytesInArbitrary) +
-		// These next two aren't arbitrary, but they are in the arbitrary section ;)
-		varUint(i.BytesInHeight) +
-		i.HeightHex
+	serialized := binary.LittleEndian.AppendUint32(nil, i.Version)
+	serialized = appendVarUint(serialized, uint64(i.NumberOfInputs))
+	serialized = append(serialized, i.PreviousOutputTransactionID[:]...)
+	serialized = binary.LittleEndian.AppendUint32(serialized, i.PreviousOutputIndex)
+	serialized = appendVarUint(serialized, uint64(i.BytesInArbitrary))
+	// This isn't arbitrary, but it is in the arbitrary section ;)
+	return appendPushData(serialized, i.Height)
 }
 
 type CoinbaseFinal struct {
-	TransactionInSequence string
+	TransactionInSequence uint32
 	OutputCount           uint
-	TxOuts                string
-	TransactionLockTime   string
+	TxOuts                []byte
+	TransactionLockTime   uint32
 }
 
-func (t *Template) CoinbaseFinal(poolPayoutPubScriptKey string) CoinbaseFinal {
-	txOutputLen, txOutput := t.coinbaseTransactionOutputs(poolPayoutPubScriptKey)
-	return CoinbaseFinal{
-		TransactionInSequence: "00000000",
-		OutputCount:           txOutputLen,
-		TxOuts:                txOutput,
-		TransactionLockTime:   "00000000",
-	}
+// An output paid straight from the coinbase, ahead of the pool's own output
+type CoinbaseOutput struct {
+	Amount       uint64
+	PubScriptKey []byte
+	PoolReward   bool // A pool fee recipient rather than a miner
 }
 
-func (f CoinbaseFinal) Serialize() string {
-	// debugCoinbaseFinalOutput(f)
-	return f.TransactionInSequence +
-		varUint(f.OutputCount) +
-		f.TxOuts +
-		f.TransactionLockTime
-}
+func (t *Template) CoinbaseFinal(poolPayoutPubScriptKey []byte, payouts []CoinbaseOutput) (CoinbaseFinal, error) {
+	txOutputLen, txOutput, err := t.coinbaseTransactionOutputs(poolPayoutPubScriptKey, payouts)
+	if err != nil {
+		return CoinbaseFinal{}, err
+	}
 
-type Coinbase struct {
-	CoinbaseInital string
-	Arbitrary      string
-	CoinbaseFinal  string
+	return CoinbaseFinal{
+		OutputCount: txOutputLen,
+		TxOuts:      txOutput,
+	}, nil
 }
 
-func (cb *Coinbase) Serialize() string {
-	// debugCoinbaseOutput(cb)
-	return cb.CoinbaseInital + cb.Arbitrary + cb.CoinbaseFinal
+func (f CoinbaseFinal) Serialize() []byte {
+	// debugCoinbaseFinalOutput(f)
+	serialized := binary.LittleEndian.AppendUint32(nil, f.TransactionInSequence)
+	serialized = appendVarUint(serialized, uint64(f.OutputCount))
+	serialized = append(serialized, f.TxOuts...)
+	return binary.LittleEndian.AppendUint32(serialized, f.TransactionLockTime)
 }
 
-func (t *Template) coinbaseTransactionOutputs(poolPubScriptKey string) (uint, string) {
+func (t *Template) coinbaseTransactionOutputs(poolPubScriptKey []byte, payouts []CoinbaseOutput) (uint, []byte, error) {
 	outputsCount := uint(0)
-	outputs := ""
-
-	if t.DefaultWitnessCommitment != "" {
-		outAmount := "0000000000000000"
-		outputs = outputs + TransactionOut(outAmount, t.DefaultWitnessCommitment)
+	var outputs []byte
+
+	if t.HasWitnessCommitment() {
+		commitment, err := hex.DecodeString(t.DefaultWitnessCommitment)
+		if err != nil {
+			return 0, nil, err
+		}
+		outputs = TransactionOut(outputs, 0, commitment)
 		outputsCount++
 	}
 
 	// Some alt coins may have additional outputs..
 
-	// Pool reward output
-	rewardAmount := fmt.Sprintf("%016x", t.CoinBaseValue)
-	rewardAmount, _ = reverseHexBytes(rewardAmount)
-	outputsCount++
-
-	outputs = outputs + TransactionOut(rewardAmount, poolPubScriptKey)
+	poolReward := uint64(t.CoinBaseValue)
+	for _, payout := range payouts {
+		if payout.Amount > poolReward {
+			m := "coinbase payouts exceed the coinbase value %v for height %v"
+			return 0, nil, fmt.Errorf(m, t.CoinBaseValue, t.Height)
+		}
+		poolReward -= payout.Amount
+		outputs = TransactionOut(outputs, payout.Amount, payout.PubScriptKey)
+		outputsCount++
+	}
 
-	return outputsCount, outputs
-}
+	// Pool reward output, whatever the payouts left over.  It's kept even when empty:
+	// the pool wallet only sees the coinbase, and unlocks the block, through this output.
+	outputs = TransactionOut(outputs, poolReward, poolPubScriptKey)
+	outputsCount++
 
-func debugCoinbaseOutput(cb *Coinbase) {
-	fmt.Println()
-	fmt.Println("**Coinbase Parts**")
-	fmt.Println()
-	fmt.Println("Initial", cb.CoinbaseInital)
-	fmt.Println("Arbitrary", cb.Arbitrary)
-	fmt.Println("Final", cb.CoinbaseFinal)
-	fmt.Println()
-	fmt.Println("Coinbase", cb.CoinbaseInital+cb.Arbitrary+cb.CoinbaseFinal)
-	fmt.Println()
+	return outputsCount, outputs, nil
 }
 
 func debugCoinbaseInitialOutput(i CoinbaseInital) {
@@ -129,20 +122,12 @@ func debugCoinbaseInitialOutput(i CoinbaseInital) {
 	fmt.Println()
 	fmt.Println("Version", i.Version)
 	fmt.Println("NumberOfInputs", i.NumberOfInputs)
-	fmt.Println("PreviousOutputTransactionID", i.PreviousOutputTransactionID)
+	fmt.Println("PreviousOutputTransactionID", hex.EncodeToString(i.PreviousOutputTransactionID[:]))
 	fmt.Println("PreviousOutputIndex", i.PreviousOutputIndex)
 	fmt.Println("BytesInArbitrary", i.BytesInArbitrary)
-	fmt.Println("BytesInHeight", i.BytesInHeight)
-	fmt.Println("HeightHex", i.HeightHex)
+	fmt.Println("Height", hex.EncodeToString(i.Height))
 	fmt.Println()
-	cbI := i.Version +
-		i.NumberOfInputs +
-		i.PreviousOutputTransactionID +
-		i.PreviousOutputIndex +
-		varUint(i.BytesInArbitrary) +
-		varUint(i.BytesInHeight) +
-		i.HeightHex
-	fmt.Println("Coinbase Initial", cbI)
+	fmt.Println("Coinbase Initial", hex.EncodeToString(i.Serialize()))
 	fmt.Println()
 }
 
@@ -152,13 +137,9 @@ func debugCoinbaseFinalOutput(f CoinbaseFinal) {
 	fmt.Println()
 	fmt.Println("TransactionInSequence", f.TransactionInSequence)
 	fmt.Println("OutputCount", f.OutputCount)
-	fmt.Println("TxOuts", f.TxOuts)
+	fmt.Println("TxOuts", hex.EncodeToString(f.TxOuts))
 	fmt.Println("TransactionLockTime", f.TransactionLockTime)
 	fmt.Println()
-	cbf := f.TransactionInSequence +
-		varUint(f.OutputCount) +
-		f.TxOuts +
-		f.TransactionLockTime
-	fmt.Println("Coinbase Final", cbf)
+	fmt.Println("Coinbase Final", hex.EncodeToString(f.Serialize()))
 	fmt.Println()
 }
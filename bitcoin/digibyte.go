@@ -4,22 +4,62 @@ import (
 	"regexp"
 )
 
-type Digibyte struct{}
+// https://github.com/DigiByte-Core/digibyte/blob/develop/src/primitives/block.h
 
-func (Digibyte) ChainName() string {
-	return "digibyte"
+const (
+	digibyteVersionAlgorithmMask = 15 << 8
+	digibyteVersionScrypt        = 0 << 8
+	digibyteVersionSha256d       = 2 << 8
+)
+
+// "digibyte" is the original, SHA256d only chain name
+type Digibyte struct {
+	algorithm string
+}
+
+func (d Digibyte) ChainName() string {
+	if d.algorithm == "" {
+		return "digibyte"
+	}
+	return "digibyte-" + d.algorithm
+}
+
+func (d Digibyte) Algorithm() string {
+	if d.algorithm == "" {
+		return AlgorithmSha256d
+	}
+	return d.algorithm
 }
 
-func (Digibyte) CoinbaseDigest(coinbase string) (string, error) {
+func (Digibyte) CoinbaseDigest(coinbase []byte) ([]byte, error) {
 	return DoubleSha256(coinbase)
 }
 
-func (Digibyte) HeaderDigest(header string) (string, error) {
+func (d Digibyte) HeaderDigest(header []byte) ([]byte, error) {
+	if d.Algorithm() == AlgorithmScrypt {
+		return ScryptDigest(header)
+	}
 	return DoubleSha256(header)
 }
 
-func (Digibyte) ShareMultiplier() float64 {
-	return 65536
+func (d Digibyte) ShareMultiplier() float64 {
+	if d.Algorithm() == AlgorithmScrypt {
+		return 65536
+	}
+	return 1
+}
+
+// getblocktemplate and getblockchaininfo's difficulties are keyed by algorithm
+func (d Digibyte) TemplateAlgorithm() string {
+	return d.Algorithm()
+}
+
+func (d Digibyte) AlgorithmVersion(version uint32) uint32 {
+	algorithmBits := uint32(digibyteVersionSha256d)
+	if d.Algorithm() == AlgorithmScrypt {
+		algorithmBits = digibyteVersionScrypt
+	}
+	return version&^digibyteVersionAlgorithmMask | algorithmBits
 }
 
 func (Digibyte) ValidMainnetAddress(address string) bool {
@@ -32,4 +72,4 @@ func (Digibyte) ValidTestnetAddress(address string) bool {
 
 func (Digibyte) MinimumConfirmations() uint {
 	return uint(100)
-}
\ No newline at end of file
+}
@@ -10,11 +10,15 @@ func (Dogecoin) ChainName() string {
 	return "dogecoin"
 }
 
-func (Dogecoin) CoinbaseDigest(coinbase string) (string, error) {
+func (Dogecoin) Algorithm() string {
+	return AlgorithmScrypt
+}
+
+func (Dogecoin) CoinbaseDigest(coinbase []byte) ([]byte, error) {
 	return DoubleSha256(coinbase)
 }
 
-func (Dogecoin) HeaderDigest(header string) (string, error) {
+func (Dogecoin) HeaderDigest(header []byte) ([]byte, error) {
 	return ScryptDigest(header)
 }
 
@@ -31,6 +35,9 @@ func (Dogecoin) ValidTestnetAddress(address string) bool {
 	return regexp.MustCompile("^(n|2)[a-km-zA-HJ-NP-Z1-9]{33}$").MatchString(address)
 }
 
+// Dogecoin Core 1.14 has no PSBT support
+func (Dogecoin) RawUnsignedTransactions() {}
+
 func (Dogecoin) MinimumConfirmations() uint {
 	return uint(251)
 }
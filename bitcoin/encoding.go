@@ -5,7 +5,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
-	"log"
+	"slices"
 )
 
 func doubleSha256Bytes(input []byte) [32]byte {
@@ -14,111 +14,43 @@ func doubleSha256Bytes(input []byte) [32]byte {
 	return sum
 }
 
-func varUint(value uint) string {
-	var buffer []byte
-	if value <= 252 {
-		buffer = []byte{byte(value)}
-	} else if value > 0xfd && value <= 0xffff {
-		buffer = make([]byte, 2)
-		binary.LittleEndian.PutUint16(buffer, uint16(value))
-		buffer = append([]byte{0xfd}, buffer...)
-	} else if value > 0xffff && value <= 0xffffffff {
-		buffer = make([]byte, 4)
-		binary.LittleEndian.PutUint32(buffer, uint32(value))
-		buffer = append([]byte{0xfe}, buffer...)
-	} else if value > 0xffffffff && value <= 0xffffffffffffffff {
-		buffer = make([]byte, 8)
-		binary.LittleEndian.PutUint64(buffer, uint64(value))
-		buffer = append([]byte{0xff}, buffer...)
-	} else {
-		panic("Too large to stream")
-	}
-
-	return hex.EncodeToString(buffer)
-}
-
-func varUint64(value uint64) string {
-	eightByteBuffer := make([]byte, 8)
-	binary.LittleEndian.PutUint64(eightByteBuffer, value)
-	cleaned := removeInsignificantBytesLittleEndian(eightByteBuffer)
-	return hex.EncodeToString(cleaned)
-}
-
-func fourLittleEndianBytes(value interface{}) []byte {
-	fourByteBuffer := make([]byte, 4)
-	switch binaryValue := value.(type) {
-	case int:
-		binary.LittleEndian.PutUint32(fourByteBuffer, uint32(binaryValue))
-	case int16:
-		binary.LittleEndian.PutUint32(fourByteBuffer, uint32(binaryValue))
-	case int32:
-		binary.LittleEndian.PutUint32(fourByteBuffer, uint32(binaryValue))
-	case uint:
-		binary.LittleEndian.PutUint32(fourByteBuffer, uint32(binaryValue))
-	case uint16:
-		binary.LittleEndian.PutUint32(fourByteBuffer, uint32(binaryValue))
-	case float32:
-		binary.LittleEndian.PutUint32(fourByteBuffer, uint32(binaryValue))
-	case uint32:
-		binary.LittleEndian.PutUint32(fourByteBuffer, binaryValue)
+// https://en.bitcoin.it/wiki/Protocol_documentation#Variable_length_integer
+func appendVarUint(buffer []byte, value uint64) []byte {
+	switch {
+	case value < 0xfd:
+		return append(buffer, byte(value))
+	case value <= 0xffff:
+		buffer = append(buffer, 0xfd)
+		return binary.LittleEndian.AppendUint16(buffer, uint16(value))
+	case value <= 0xffffffff:
+		buffer = append(buffer, 0xfe)
+		return binary.LittleEndian.AppendUint32(buffer, uint32(value))
 	default:
-		log.Fatalln("Unable to write 4 byte stream: " + value.(string))
+		buffer = append(buffer, 0xff)
+		return binary.LittleEndian.AppendUint64(buffer, value)
 	}
-
-	return fourByteBuffer
 }
 
-func eightLittleEndianBytes(value interface{}) []byte {
-	eightByteBuffer := make([]byte, 8)
-	switch binaryValue := value.(type) {
-	case int:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case int16:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case int32:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case int64:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case uint:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case uint16:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case uint32:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case uint64:
-		binary.LittleEndian.PutUint64(eightByteBuffer, binaryValue)
-	case float32:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	case float64:
-		binary.LittleEndian.PutUint64(eightByteBuffer, uint64(binaryValue))
-	default:
-		log.Fatalln("Unable to write 8 byte stream: " + value.(string))
-	}
-
-	return eightByteBuffer
+func varUint(value uint) string {
+	return hex.EncodeToString(appendVarUint(nil, uint64(value)))
 }
 
-func removeInsignificantBytesLittleEndian(bytes []byte) []byte {
-	var cleaned []byte
-
-	weReachedASignificantByte := false
-	for _, b := range bytes {
-		if weReachedASignificantByte && b == 0 {
-			continue
-		}
-		cleaned = append(cleaned, b)
-		if b != 0 {
-
-			weReachedASignificantByte = true
-		}
+// Minimally encoded, sign-aware little endian script number (BIP34 heights)
+func scriptNumber(value uint64) []byte {
+	var number []byte
+	for value > 0 {
+		number = append(number, byte(value&0xff))
+		value >>= 8
 	}
-
-	return cleaned
+	if len(number) > 0 && number[len(number)-1]&0x80 != 0 {
+		number = append(number, 0x00)
+	}
+	return number
 }
 
-func bytesWithLengthHeader(bytes []byte) []byte {
-	lenHeader := []byte{byte(len(bytes))}
-	return append(lenHeader, bytes...)
+func appendPushData(buffer, data []byte) []byte {
+	buffer = append(buffer, byte(len(data)))
+	return append(buffer, data...)
 }
 
 func reverse(b []byte) []byte {
@@ -131,28 +63,99 @@ func reverse(b []byte) []byte {
 	return r
 }
 
-func reverseHexBytes(hex string) (string, error) {
-	if len(hex)%2 != 0 {
+func reverseInPlace(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+func reverseHexBytes(hexString string) (string, error) {
+	bytes, err := hex.DecodeString(hexString)
+	if err != nil {
 		return "", errors.New("string must be divisible by 2 to be a byte string")
 	}
-	o := ""
-	l := len(hex)
-	for i := l; i > 0; i = i - 2 {
-		o = o + hex[i-2:i]
+	reverseInPlace(bytes)
+	return hex.EncodeToString(bytes), nil
+}
+
+// Stratum sends the previous block hash as 4 byte words in reverse order
+func reverse4ByteWords(b []byte) []byte {
+	r := make([]byte, len(b))
+	words := len(b) / 4
+	for i := 0; i < words; i++ {
+		copy(r[i*4:(i+1)*4], b[len(b)-(i+1)*4:len(b)-i*4])
 	}
-	return o, nil
+	return r
 }
 
-func reverseHex4Bytes(hex string) (string, error) {
-	if len(hex)%8 != 0 {
-		return "", errors.New("string must be divisible by 8 to represent 4 byte array")
+// Decodes without allocating; the length of dst sets the expected length of hexString
+func decodeHexInto(dst []byte, hexString string) error {
+	if len(hexString) != len(dst)*2 {
+		return errors.New("unexpected hex length")
 	}
+	for i := range dst {
+		high, ok := fromHexChar(hexString[i*2])
+		if !ok {
+			return hex.InvalidByteError(hexString[i*2])
+		}
+		low, ok := fromHexChar(hexString[i*2+1])
+		if !ok {
+			return hex.InvalidByteError(hexString[i*2+1])
+		}
+		dst[i] = high<<4 | low
+	}
+	return nil
+}
 
-	var o string
+func appendHexString(dst []byte, hexString string) ([]byte, error) {
+	if len(hexString)%2 != 0 {
+		return dst, hex.ErrLength
+	}
+	start, length := len(dst), len(hexString)/2
+	dst = slices.Grow(dst, length)[:start+length]
+	err := decodeHexInto(dst[start:], hexString)
+	return dst, err
+}
 
-	for l, i := len(hex), 0; i < l/8; i++ {
-		o = o + hex[l-8*(i+1):(l-(8*i))]
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
 	}
+	return 0, false
+}
 
-	return o, nil
+// The inverse of appendVarUint, returns the value and the bytes it took
+func readVarUint(buffer []byte) (uint64, int, error) {
+	if len(buffer) < 1 {
+		return 0, 0, errors.New("truncated varint")
+	}
+
+	size := 1
+	switch buffer[0] {
+	case 0xfd:
+		size = 3
+	case 0xfe:
+		size = 5
+	case 0xff:
+		size = 9
+	}
+	if len(buffer) < size {
+		return 0, 0, errors.New("truncated varint")
+	}
+
+	switch size {
+	case 3:
+		return uint64(binary.LittleEndian.Uint16(buffer[1:])), size, nil
+	case 5:
+		return uint64(binary.LittleEndian.Uint32(buffer[1:])), size, nil
+	case 9:
+		return binary.LittleEndian.Uint64(buffer[1:]), size, nil
+	default:
+		return uint64(buffer[0]), size, nil
+	}
 }
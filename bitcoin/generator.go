@@ -8,15 +8,15 @@ import (
 )
 
 type BlockGenerator interface {
-	MakeHeader(extranonce, nonce string) (string, error) // On aux generation, on work verfication, and possibily even work submission
-	Header() string
+	MakeHeader(extranonce, nonce, nonceTime string) error // On aux generation, on work verfication, and possibily even work submission
+	HeaderHashed() (string, error)
 	Sum() (*big.Int, error)  // On work verification, many, more than than header generation
 	Submit() (string, error) // On submission
 }
 
 var jobCounter int
 
-func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary, poolPayoutPubScriptKey string, reservedArbitraryByteLength int) (*BitcoinBlock, Work, error) { // On trigger
+func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary, poolPayoutPubScriptKey string, reservedArbitraryByteLength int, coinbasePayouts []CoinbaseOutput) (*BitcoinBlock, Work, error) { // On trigger
 	if template == nil {
 		return nil, nil, errors.New("Template cannot be null")
 	}
@@ -27,30 +27,64 @@ func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary,
 	block.init(GetChain(chainName))
 	block.Template = template
 
-	block.ReversePrevBlockHash, err = reverseHex4Bytes(block.Template.PrevBlockHash)
+	err = block.Template.validateWitnessCommitment()
 	if err != nil {
-		m := "invalid previous block hash hex: " + err.Error()
-		return nil, nil, errors.New(m)
+		return nil, nil, err
+	}
+
+	err = block.Template.validateMweb()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prevBlockHash, err := hex.DecodeString(block.Template.PrevBlockHash)
+	if err != nil || len(prevBlockHash) != 32 {
+		return nil, nil, fmt.Errorf("invalid previous block hash hex: %v", block.Template.PrevBlockHash)
+	}
+	block.ReversePrevBlockHash = hex.EncodeToString(reverse4ByteWords(prevBlockHash))
+
+	version := uint32(block.Template.Version)
+	if multiAlgorithm, ok := block.Chain.(MultiAlgorithmChain); ok {
+		version = multiAlgorithm.AlgorithmVersion(version)
+	}
+
+	block.header, err = makeBlockHeader(version, block.Template.PrevBlockHash, block.Template.Bits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	poolPubScriptKey, err := hex.DecodeString(poolPayoutPubScriptKey)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("invalid pool payout script"), err)
 	}
 
-	arbitraryBytes := bytesWithLengthHeader([]byte(arbitrary))
+	arbitraryBytes := appendPushData(nil, []byte(arbitrary))
 	arbitraryByteLength := uint(len(arbitraryBytes) + reservedArbitraryByteLength)
-	arbitraryHex := hex.EncodeToString(arbitraryBytes)
 
-	block.CoinbaseInitial = block.Template.CoinbaseInitial(arbitraryByteLength).Serialize()
-	block.CoinbaseFinal = arbitraryHex + block.Template.CoinbaseFinal(poolPayoutPubScriptKey).Serialize()
-	block.MerkleSteps, err = block.Template.MerkleSteps()
+	coinbaseFinal, err := block.Template.CoinbaseFinal(poolPubScriptKey, coinbasePayouts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block.payouts = coinbasePayouts
+	block.coinbasePrefix = block.Template.CoinbaseInitial(arbitraryByteLength).Serialize()
+	block.coinbaseSuffix = append(arbitraryBytes, coinbaseFinal.Serialize()...)
+	block.merkleBranch, err = block.Template.MerkleSteps()
 	if err != nil {
 		return nil, nil, err
 	}
 
+	block.CoinbaseInitial = hex.EncodeToString(block.coinbasePrefix)
+	block.CoinbaseFinal = hex.EncodeToString(block.coinbaseSuffix)
+	block.MerkleSteps = merkleStepsHex(block.merkleBranch)
+
 	work := make(Work, 8)
 	work[0] = fmt.Sprintf("%08x", jobCounter) // Job ID
 	work[1] = block.ReversePrevBlockHash
 	work[2] = block.CoinbaseInitial
 	work[3] = block.CoinbaseFinal
 	work[4] = block.MerkleSteps
-	work[5] = fmt.Sprintf("%08x", block.Template.Version)
+	work[5] = fmt.Sprintf("%08x", version)
 	work[6] = block.Template.Bits
 	work[7] = fmt.Sprintf("%x", block.Template.CurrentTime)
 
@@ -59,103 +93,114 @@ func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary,
 	return &block, work, nil
 }
 
-func (b *BitcoinBlock) MakeHeader(extranonce, nonce, nonceTime string) (string, error) {
+// Call on a copy of the job's block; the header, coinbase and hash belong to one share
+func (b *BitcoinBlock) MakeHeader(extranonce, nonce, nonceTime string) error {
 	if b.Template == nil {
-		return "", errors.New("generate work first")
+		return errors.New("generate work first")
 	}
 
 	var err error
-	coinbase := Coinbase{
-		CoinbaseInital: b.CoinbaseInitial,
-		Arbitrary:      extranonce,
-		CoinbaseFinal:  b.CoinbaseFinal,
-	}
-
-	b.Coinbase = coinbase.Serialize()
-	coinbaseHashed, err := b.CoinbaseHashed()
+	coinbaseLength := len(b.coinbasePrefix) + len(extranonce)/2 + len(b.coinbaseSuffix)
+	b.coinbase = make([]byte, 0, coinbaseLength)
+	b.coinbase = append(b.coinbase, b.coinbasePrefix...)
+	b.coinbase, err = appendHexString(b.coinbase, extranonce)
 	if err != nil {
-		return "", err
+		return errors.Join(errors.New("invalid extranonce"), err)
 	}
+	b.coinbase = append(b.coinbase, b.coinbaseSuffix...)
 
-	merkleRoot, err := makeHeaderMerkleRoot(coinbaseHashed, b.MerkleSteps)
+	coinbaseHashed, err := b.coinbaseDigest()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	t := b.Template
+	b.header.setMerkleRoot(makeHeaderMerkleRoot(coinbaseHashed, b.merkleBranch))
 
-	b.Header, err = blockHeader(uint(t.Version), t.PrevBlockHash, merkleRoot, nonceTime, t.Bits, nonce)
+	err = b.header.setNonceTime(nonceTime)
+	if err != nil {
+		return errors.Join(errors.New("invalid ntime"), err)
+	}
 
+	err = b.header.setNonce(nonce)
 	if err != nil {
-		return "", err
+		return errors.Join(errors.New("invalid nonce"), err)
 	}
 
-	return b.Header, nil
+	b.headerMade = true
+	b.hashed = false
+
+	return nil
 }
 
+// Call on the same copy as MakeHeader
+func (b *BitcoinBlock) RollVersion(versionBits, mask uint32) {
+	b.header.setVersionBits(versionBits, mask)
+	b.hashed = false
+}
+
+func (b *BitcoinBlock) HeaderHex() string {
+	return hex.EncodeToString(b.header[:])
+}
+
+// Block hash, as the chain and explorers display it
 func (b *BitcoinBlock) HeaderHashed() (string, error) {
-	// TODO - break out headerdigest vs blockdigest
-	header, err := b.Chain.CoinbaseDigest(b.Header)
-	if err != nil {
-		return "", err
+	if !b.headerMade {
+		return "", errors.New("generate header first")
 	}
-	// Not sure if this is for litecoin only, but..
-	header, err = reverseHexBytes(header)
+	// TODO - break out headerdigest vs blockdigest
+	header := doubleSha256Bytes(b.header[:])
+	reverseInPlace(header[:])
+	return hex.EncodeToString(header[:]), nil
+}
+
+func (b *BitcoinBlock) coinbaseDigest() ([32]byte, error) {
+	var digest [32]byte
+	coinbaseHashed, err := b.Chain.CoinbaseDigest(b.coinbase)
 	if err != nil {
-		return "", err
+		return digest, err
 	}
-	return header, nil
+	copy(digest[:], coinbaseHashed)
+	return digest, nil
 }
 
 func (b *BitcoinBlock) CoinbaseHashed() (string, error) {
-	return b.Chain.CoinbaseDigest(b.Coinbase)
+	if !b.headerMade {
+		return "", errors.New("generate header first")
+	}
+	digest, err := b.coinbaseDigest()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest[:]), nil
 }
 
 func (b *BitcoinBlock) Sum() (*big.Int, error) {
 	if b.Chain == nil {
 		return nil, errors.New("calculateSum: Missing blockchain interface")
 	}
-	if b.Header == "" {
+	if !b.headerMade {
 		return nil, errors.New("generate header first")
 	}
 
-	digest, err := b.Chain.HeaderDigest(b.Header)
-	if err != nil {
-		return nil, err
-	}
-
-	digest, err = reverseHexBytes(digest)
-	if err != nil {
-		return nil, err
-	}
-
-	b.Hash = digest  // Changed from b.hash to b.Hash
-
-	digestBytes, err := hex.DecodeString(digest)
-	if err != nil {
-		return nil, err
+	if !b.hashed {
+		digest, err := b.Chain.HeaderDigest(b.header[:])
+		if err != nil {
+			return nil, err
+		}
+		copy(b.hash[:], digest)
+		reverseInPlace(b.hash[:])
+		b.hashed = true
 	}
 
-	return new(big.Int).SetBytes(digestBytes), nil
+	return new(big.Int).SetBytes(b.hash[:]), nil
 }
 
 func (b *BitcoinBlock) Submit() (string, error) {
-	if b.Header == "" {  // Changed from b.header to b.Header
+	if !b.headerMade {
 		return "", errors.New("generate header first")
 	}
 
-	transactionPool := make([]string, len(b.Template.Transactions))
-	for i, transaction := range b.Template.Transactions {
-		transactionPool[i] = transaction.Data
-	}
-
-	submission := b.createSubmissionHex()
-
-	if b.Template.MimbleWimble != "" {
-		submission = submission + "01" + b.Template.MimbleWimble
-	}
-
-	return submission, nil
+	return b.createSubmissionHex()
 }
 
 func debugMerkleSteps(block BitcoinBlock) {
@@ -167,7 +212,7 @@ func debugMerkleSteps(block BitcoinBlock) {
 
 	fmt.Println()
 	fmt.Println("Steps")
-	for i, step := range block.MerkleSteps {  // Changed from block.merkleSteps to block.MerkleSteps
+	for i, step := range block.MerkleSteps {
 		fmt.Println(i+1, step)
 	}
 	fmt.Println()
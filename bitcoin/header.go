@@ -1,63 +1,89 @@
 package bitcoin
 
 import (
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 )
 
 // https://developer.bitcoin.org/reference/block_chain.html#block-headers
 
-func blockHeader(version uint, previousBlockHash, merkleRootHex, nTime, bits, nonce string) (string, error) {
-	versionBytes := fourLittleEndianBytes(version)
+const (
+	headerLength           = 80
+	headerVersionStart     = 0
+	headerPrevHashStart    = 4
+	headerMerkleRootStart  = 36
+	headerNonceTimeStart   = 68
+	headerBitsStart        = 72
+	headerNonceStart       = 76
+	headerFieldLength      = 4
+	headerHashFieldsLength = 32
+)
 
-	prevBlockHash, err := hex.DecodeString(previousBlockHash)
-	if err != nil {
-		return "", err
-	}
+type blockHeader [headerLength]byte
 
-	merkleRoot, err := hex.DecodeString(merkleRootHex)
-	if err != nil {
-		return "", err
-	}
+// Fills everything that stays the same for every share of a job
+func makeBlockHeader(version uint32, previousBlockHash, bits string) (blockHeader, error) {
+	var header blockHeader
 
-	// Often, this is the same value as blockTemplate.CurrTime
-	nonceTime, err := hex.DecodeString(nTime)
-	if err != nil {
-		return "", err
-	}
+	binary.LittleEndian.PutUint32(header[headerVersionStart:], version)
 
-	bitsHex, err := hex.DecodeString(bits)
+	prevHash := header[headerPrevHashStart : headerPrevHashStart+headerHashFieldsLength]
+	err := decodeHexInto(prevHash, previousBlockHash)
 	if err != nil {
-		return "", err
+		return header, err
 	}
+	reverseInPlace(prevHash)
 
-	nonceHex, err := hex.DecodeString(nonce)
+	err = header.setReversedField(headerBitsStart, bits)
 	if err != nil {
-		return "", err
+		return header, err
 	}
 
-	blockHeader := versionBytes
-	blockHeader = append(blockHeader, reverse(prevBlockHash)...)
-	blockHeader = append(blockHeader, merkleRoot...)
-	blockHeader = append(blockHeader, reverse(nonceTime)...)
-	blockHeader = append(blockHeader, reverse(bitsHex)...)
-	blockHeader = append(blockHeader, reverse(nonceHex)...)
+	return header, nil
+}
+
+func (h *blockHeader) setMerkleRoot(merkleRoot [32]byte) {
+	copy(h[headerMerkleRootStart:headerMerkleRootStart+headerHashFieldsLength], merkleRoot[:])
+}
+
+// Often, nTime is the same value as blockTemplate.CurrTime
+func (h *blockHeader) setNonceTime(nTime string) error {
+	return h.setReversedField(headerNonceTimeStart, nTime)
+}
+
+func (h *blockHeader) setNonce(nonce string) error {
+	return h.setReversedField(headerNonceStart, nonce)
+}
 
-	// headerDebugOutput(versionBytes, prevBlockHash, merkleRoot, nonceTime, bitsHex, nonceHex, blockHeader)
-	return hex.EncodeToString(blockHeader), nil
+// BIP310 version rolling, only the bits in mask are taken from versionBits
+func (h *blockHeader) setVersionBits(versionBits, mask uint32) {
+	version := binary.LittleEndian.Uint32(h[headerVersionStart:])
+	version = version&^mask | versionBits&mask
+	binary.LittleEndian.PutUint32(h[headerVersionStart:], version)
+}
+
+func (h *blockHeader) setReversedField(start int, fieldHex string) error {
+	field := h[start : start+headerFieldLength]
+	err := decodeHexInto(field, fieldHex)
+	if err != nil {
+		return err
+	}
+	reverseInPlace(field)
+	return nil
 }
 
-func headerDebugOutput(versionBytes, prevBlockHash, merkleRoot, nonceTime, bitsHex, nonceHex, blockHeader []byte) {
+func headerDebugOutput(header blockHeader) {
 	fmt.Println()
 	fmt.Println("**Block HEADER**")
 	fmt.Println()
-	fmt.Println("version", hex.EncodeToString(versionBytes))
-	fmt.Println("prevBlockHash", hex.EncodeToString(reverse(prevBlockHash)))
-	fmt.Println("merkleRoot", hex.EncodeToString(merkleRoot))
-	fmt.Println("nonceTime", hex.EncodeToString(reverse(nonceTime)))
-	fmt.Println("bitsHex", hex.EncodeToString(reverse(bitsHex)))
-	fmt.Println("nonceHex", hex.EncodeToString(reverse(nonceHex)))
+	fmt.Println("version", hex.EncodeToString(header[headerVersionStart:headerPrevHashStart]))
+	fmt.Println("prevBlockHash", hex.EncodeToString(reverse(header[headerPrevHashStart:headerMerkleRootStart])))
+	fmt.Println("merkleRoot", hex.EncodeToString(header[headerMerkleRootStart:headerNonceTimeStart]))
+	fmt.Println("nonceTime", hex.EncodeToString(reverse(header[headerNonceTimeStart:headerBitsStart])))
+	fmt.Println("bitsHex", hex.EncodeToString(reverse(header[headerBitsStart:headerNonceStart])))
+	fmt.Println("nonceHex", hex.EncodeToString(reverse(header[headerNonceStart:])))
 	fmt.Println()
-	fmt.Println("Header", hex.EncodeToString(blockHeader))
+	fmt.Println("Header", hex.EncodeToString(header[:]))
 	fmt.Println()
 }
@@ -10,11 +10,15 @@ func (Litecoin) ChainName() string {
 	return "litecoin"
 }
 
-func (Litecoin) CoinbaseDigest(coinbase string) (string, error) {
+func (Litecoin) Algorithm() string {
+	return AlgorithmScrypt
+}
+
+func (Litecoin) CoinbaseDigest(coinbase []byte) ([]byte, error) {
 	return DoubleSha256(coinbase)
 }
 
-func (Litecoin) HeaderDigest(header string) (string, error) {
+func (Litecoin) HeaderDigest(header []byte) ([]byte, error) {
 	return ScryptDigest(header)
 }
 
@@ -33,3 +37,15 @@ func (Litecoin) ValidTestnetAddress(address string) bool {
 func (Litecoin) MinimumConfirmations() uint {
 	return uint(BitcoinMinConfirmations)
 }
+
+func (Litecoin) RetargetInterval() uint {
+	return 2016
+}
+
+func (Litecoin) HalvingInterval() uint {
+	return 840000
+}
+
+func (Litecoin) InitialSubsidy() uint {
+	return 50 * 100000000
+}
@@ -6,87 +6,62 @@ import (
 
 // https://github.com/zone117x/node-stratum-pool/blob/master/lib/merkleTree.js#L9
 
-func (t *Template) MerkleSteps() ([]string, error) {
-	transactionIDs := make([]string, len(t.Transactions))
+func (t *Template) MerkleSteps() ([][32]byte, error) {
+	transactionIDs := make([][32]byte, len(t.Transactions))
 	for i, transaction := range t.Transactions {
+		err := decodeHexInto(transactionIDs[i][:], transaction.ID)
+		if err != nil {
+			return nil, err
+		}
 		// Little endian writes
-		idReversed, _ := reverseHexBytes(transaction.ID)
-		transactionIDs[i] = idReversed
+		reverseInPlace(transactionIDs[i][:])
 	}
 
-	return templateMerkleBranchSteps(transactionIDs)
+	return templateMerkleBranchSteps(transactionIDs), nil
 }
 
-func templateMerkleBranchSteps(transactionIDs []string) ([]string, error) {
-	steps := []string{}
-	levelLength := len(transactionIDs)
-
-	if levelLength == 0 {
-		return steps, nil
-	}
-
-	var level []string
-	startJoinAt := 2
+// The coinbase is left out of transactionIDs; it's always the first leaf
+func templateMerkleBranchSteps(transactionIDs [][32]byte) [][32]byte {
+	var steps [][32]byte
+	level := transactionIDs
 
-	rightShift := []string{""}
-	level = append(rightShift, transactionIDs...)
-	levelLength++
-
-	for {
-		if levelLength == 1 {
-			break
-		}
-
-		steps = append(steps, level[1])
-
-		if levelLength%2 == 1 {
-			level = append(level, level[len(level)-1])
-		}
+	for len(level) > 0 {
+		steps = append(steps, level[0])
 
-		var levelJoins []string
-		for i := startJoinAt; i < levelLength; i += 2 {
-			joined, err := join(level[i], level[i+1])
-			if err != nil {
-				return steps, err
+		joinable := level[1:]
+		levelJoins := make([][32]byte, 0, (len(joinable)+1)/2)
+		for i := 0; i < len(joinable); i += 2 {
+			right := i + 1
+			if right == len(joinable) {
+				right = i
 			}
-			levelJoins = append(levelJoins, joined)
+			levelJoins = append(levelJoins, join(joinable[i], joinable[right]))
 		}
-		level = append(rightShift, levelJoins...)
-		levelLength = len(level)
+		level = levelJoins
 	}
 
-	return steps, nil
+	return steps
 }
 
-func join(one, two string) (string, error) {
-	oneBytes, err := hex.DecodeString(one)
-	if err != nil {
-		return "", err
-	}
-	twoBytes, err := hex.DecodeString(two)
-	if err != nil {
-		return "", err
-	}
-
-	merged := doubleSha256Bytes(append(oneBytes, twoBytes...))
-
-	mergedHex := hex.EncodeToString(merged[:])
-	return mergedHex, nil
+func join(one, two [32]byte) [32]byte {
+	var joined [64]byte
+	copy(joined[:32], one[:])
+	copy(joined[32:], two[:])
+	return doubleSha256Bytes(joined[:])
 }
 
-func makeHeaderMerkleRoot(coinbase string, merkleBranchSteps []string) (string, error) {
-	block, err := hex.DecodeString(coinbase)
-	if err != nil {
-		return "", err
-	}
+func makeHeaderMerkleRoot(coinbase [32]byte, merkleBranchSteps [][32]byte) [32]byte {
+	root := coinbase
 	for _, branch := range merkleBranchSteps {
-		branchBytes, err := hex.DecodeString(branch)
-		if err != nil {
-			return "", err
-		}
-		joined := doubleSha256Bytes(append(block, branchBytes...))
-		block = joined[:]
+		root = join(root, branch)
 	}
+	return root
+}
 
-	return hex.EncodeToString(block), nil
+func merkleStepsHex(merkleBranchSteps [][32]byte) []string {
+	steps := make([]string, len(merkleBranchSteps))
+	for i, step := range merkleBranchSteps {
+		steps[i] = hex.EncodeToString(step[:])
+	}
+	return steps
 }
@@ -0,0 +1,299 @@
+package bitcoin
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// https://github.com/litecoin-project/lips/blob/master/lip-0002.mediawiki
+// https://github.com/litecoin-project/lips/blob/master/lip-0003.mediawiki
+
+const (
+	mwebBlockPresent          = "01" // The extension block is serialized as an optional pointer
+	mwebTransactionFlag       = 0x08
+	transactionExtendedMarker = "00"
+)
+
+// The HogEx is the only template transaction that can carry the MWEB flag;
+// MWEB-only transactions live inside the extension block itself.
+func (t Transaction) IsHogEx() bool {
+	versionLength := 8
+	if len(t.Data) < versionLength+4 {
+		return false
+	}
+
+	if t.Data[versionLength:versionLength+2] != transactionExtendedMarker {
+		return false
+	}
+
+	flags, err := strconv.ParseUint(t.Data[versionLength+2:versionLength+4], 16, 8)
+	if err != nil {
+		return false
+	}
+
+	return flags&mwebTransactionFlag != 0
+}
+
+func (t *Template) HasMweb() bool {
+	return t.MimbleWimble != ""
+}
+
+func (t *Template) validateMweb() error {
+	hogExIndex := -1
+	for i, transaction := range t.Transactions {
+		if !transaction.IsHogEx() {
+			continue
+		}
+		if hogExIndex != -1 {
+			m := "template for height %v has more than one HogEx transaction"
+			return fmt.Errorf(m, t.Height)
+		}
+		hogExIndex = i
+	}
+
+	if !t.HasMweb() {
+		if hogExIndex != -1 {
+			m := "template for height %v has a HogEx transaction but no mweb block"
+			return fmt.Errorf(m, t.Height)
+		}
+		return nil
+	}
+
+	if _, err := hex.DecodeString(t.MimbleWimble); err != nil {
+		return errors.Join(errors.New("invalid mweb block hex"), err)
+	}
+
+	if hogExIndex == -1 {
+		m := "template for height %v has an mweb block but no HogEx transaction"
+		return fmt.Errorf(m, t.Height)
+	}
+
+	if hogExIndex != len(t.Transactions)-1 {
+		m := "template for height %v has its HogEx transaction at %v of %v, it must be last"
+		return fmt.Errorf(m, t.Height, hogExIndex, len(t.Transactions))
+	}
+
+	return nil
+}
+
+// litecoind only reads the extension block when the final transaction is the HogEx
+func (t *Template) mwebBlockSerialized() string {
+	if !t.HasMweb() {
+		return ""
+	}
+	return mwebBlockPresent + t.MimbleWimble
+}
+
+// Bitcoin's VARINT, as opposed to the CompactSize varUint, used by MWEB headers
+func appendMwebVarInt(buffer []byte, value uint64) []byte {
+	var reversed [10]byte
+	length := 0
+	for {
+		reversed[length] = byte(value & 0x7f)
+		if length > 0 {
+			reversed[length] |= 0x80
+		}
+		if value <= 0x7f {
+			break
+		}
+		value = (value >> 7) - 1
+		length++
+	}
+	for ; length >= 0; length-- {
+		buffer = append(buffer, reversed[length])
+	}
+	return buffer
+}
+
+func readMwebVarInt(buffer []byte) (uint64, int, error) {
+	value := uint64(0)
+	for i, b := range buffer {
+		if i > 9 {
+			break
+		}
+		value = (value << 7) | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return value, i + 1, nil
+		}
+		value++
+	}
+	return 0, 0, errors.New("invalid MWEB varint")
+}
+
+type mwebHeader struct {
+	Height        uint64
+	OutputRoot    [32]byte
+	KernelRoot    [32]byte
+	LeafsetRoot   [32]byte
+	KernelOffset  [32]byte
+	StealthOffset [32]byte
+	OutputMMRSize uint64
+	KernelMMRSize uint64
+}
+
+func readMwebHeader(data []byte) (mwebHeader, error) {
+	var header mwebHeader
+	var size int
+	var err error
+
+	header.Height, size, err = readMwebVarInt(data)
+	if err != nil {
+		return header, err
+	}
+	data = data[size:]
+
+	for _, field := range []*[32]byte{&header.OutputRoot, &header.KernelRoot, &header.LeafsetRoot,
+		&header.KernelOffset, &header.StealthOffset} {
+		if len(data) < 32 {
+			return header, errors.New("truncated MWEB header")
+		}
+		copy(field[:], data)
+		data = data[32:]
+	}
+
+	header.OutputMMRSize, size, err = readMwebVarInt(data)
+	if err != nil {
+		return header, err
+	}
+	header.KernelMMRSize, _, err = readMwebVarInt(data[size:])
+	return header, err
+}
+
+func (h mwebHeader) serialize() []byte {
+	serialized := appendMwebVarInt(nil, h.Height)
+	serialized = append(serialized, h.OutputRoot[:]...)
+	serialized = append(serialized, h.KernelRoot[:]...)
+	serialized = append(serialized, h.LeafsetRoot[:]...)
+	serialized = append(serialized, h.KernelOffset[:]...)
+	serialized = append(serialized, h.StealthOffset[:]...)
+	serialized = appendMwebVarInt(serialized, h.OutputMMRSize)
+	return appendMwebVarInt(serialized, h.KernelMMRSize)
+}
+
+func (h mwebHeader) hash() ([32]byte, error) {
+	return blake3Sum256(h.serialize())
+}
+
+// The HogEx's first output commits to the extension block's header hash
+func mwebHeaderCommitment(script []byte) ([32]byte, bool) {
+	var hash [32]byte
+	if len(script) != 34 || script[0] != 0x59 || script[1] != 0x20 {
+		return hash, false
+	}
+	copy(hash[:], script[2:])
+	return hash, true
+}
+
+// The MWEB state a block leaves behind, which the next block's HogEx and extension block build on
+type MwebTip struct {
+	hogExID    [32]byte // Internal byte order
+	hogExValue uint64
+	header     mwebHeader
+}
+
+// Reads the HogEx and extension block header from a serialized block.  The header's hash must
+// match the HogEx commitment, so a serialization this pool gets wrong is refused here.
+func ParseMwebTip(blockHex string) (*MwebTip, error) {
+	block, err := hex.DecodeString(blockHex)
+	if err != nil {
+		return nil, err
+	}
+	if len(block) < 80 {
+		return nil, errors.New("truncated block")
+	}
+
+	position := 80
+	count, size, err := readVarUint(block[position:])
+	if err != nil {
+		return nil, err
+	}
+	position += size
+
+	var last parsedTransaction
+	for i := uint64(0); i < count; i++ {
+		last, size, err = readTransaction(block[position:])
+		if err != nil {
+			return nil, fmt.Errorf("block transaction %v: %w", i, err)
+		}
+		position += size
+	}
+
+	if !last.HogEx || len(last.Outputs) < 1 {
+		return nil, errors.New("block doesn't end with a HogEx transaction")
+	}
+	commitment, ok := mwebHeaderCommitment(last.Outputs[0].Script)
+	if !ok {
+		return nil, errors.New("HogEx doesn't pay to an MWEB header commitment")
+	}
+
+	if position >= len(block) || block[position] != 0x01 {
+		return nil, errors.New("block has no extension block")
+	}
+	header, err := readMwebHeader(block[position+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := header.hash()
+	if err != nil {
+		return nil, err
+	}
+	if hash != commitment {
+		return nil, errors.New("MWEB header hash doesn't match the HogEx commitment")
+	}
+
+	return &MwebTip{
+		hogExID:    last.ID(),
+		hogExValue: last.Outputs[0].Value,
+		header:     header,
+	}, nil
+}
+
+// An extension block without transactions on top of the tip, and the HogEx committing to it.
+// Nothing is added or spent so the MMRs and leafset carry over and the offsets are zero.
+func (tip *MwebTip) nextEmpty() (Transaction, string, error) {
+	header := tip.header
+	header.Height++
+	header.KernelOffset = [32]byte{}
+	header.StealthOffset = [32]byte{}
+
+	hash, err := header.hash()
+	if err != nil {
+		return Transaction{}, "", err
+	}
+
+	emptyBody := []byte{0, 0, 0} // No inputs, outputs or kernels
+	extensionBlock := append(header.serialize(), emptyBody...)
+
+	commitment := append([]byte{0x59, 0x20}, hash[:]...)
+	inputs := appendVarUint(nil, 1)
+	inputs = append(inputs, tip.hogExID[:]...)
+	inputs = binary.LittleEndian.AppendUint32(inputs, 0) // The previous HogEx's MWEB output
+	inputs = appendVarUint(inputs, 0)
+	inputs = binary.LittleEndian.AppendUint32(inputs, 0xffffffff)
+	outputs := TransactionOut(appendVarUint(nil, 1), tip.hogExValue, commitment)
+
+	version := binary.LittleEndian.AppendUint32(nil, 2)
+	lockTime := binary.LittleEndian.AppendUint32(nil, 0)
+
+	stripped := append(append(append(append([]byte{}, version...), inputs...), outputs...), lockTime...)
+	serialized := append(append([]byte{}, version...), 0x00, mwebTransactionFlag)
+	serialized = append(append(serialized, inputs...), outputs...)
+	serialized = append(serialized, 0x00) // No MWEB transaction, which is what makes it the HogEx
+	serialized = append(serialized, lockTime...)
+
+	// Without witnesses or an MWEB transaction the wtxid is the txid
+	id := doubleSha256Bytes(stripped)
+	reverseInPlace(id[:])
+	hogEx := Transaction{
+		Data:   hex.EncodeToString(serialized),
+		ID:     hex.EncodeToString(id[:]),
+		Hash:   hex.EncodeToString(id[:]),
+		Weight: uint(len(serialized)) * 4,
+	}
+
+	return hogEx, hex.EncodeToString(extensionBlock), nil
+}
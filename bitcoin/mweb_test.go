@@ -0,0 +1,308 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// These fixtures are hand built to getblocktemplate's post-MWEB Litecoin layout: a segwit
+// spend, then the HogEx, whose serialization carries the MWEB flag and a null MWEB tx.
+// mweb-submission.hex is this code's own output, so it only guards against regressions;
+// TestMainnetMwebBlock checks the same paths against a block from the chain.
+const (
+	mwebPoolScript  = "00143b8e8b5c0f6a2c1d9b7e4f3a2d1c0b9a8f7e6d5c"
+	mwebExtranonce  = "0000000100000000"
+	mwebNonce       = "1a2b3c4d"
+	mwebNonceTime   = "66672c80"
+	mwebArbitrary   = "/dogepool/"
+	mwebReservation = 8
+)
+
+func loadMwebTemplate(t *testing.T) *Template {
+	t.Helper()
+	raw, err := os.ReadFile("testdata/mweb-template.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template Template
+	err = json.Unmarshal(raw, &template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &template
+}
+
+func TestIsHogEx(t *testing.T) {
+	template := loadMwebTemplate(t)
+
+	if template.Transactions[0].IsHogEx() {
+		t.Error("segwit spend detected as a HogEx")
+	}
+	if !template.Transactions[1].IsHogEx() {
+		t.Error("HogEx not detected")
+	}
+	if (Transaction{Data: "0200"}).IsHogEx() {
+		t.Error("truncated transaction detected as a HogEx")
+	}
+
+	for _, transaction := range template.Transactions {
+		parsed, _, err := readTransaction(mustDecodeHex(t, transaction.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := parsed.ID()
+		if hex.EncodeToString(reverse(id[:])) != transaction.ID {
+			t.Errorf("parsed txid differs from %v", transaction.ID)
+		}
+	}
+}
+
+func TestValidateMweb(t *testing.T) {
+	template := loadMwebTemplate(t)
+	if err := template.validateMweb(); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+
+	hogEx, spend := template.Transactions[1], template.Transactions[0]
+
+	misplaced := *template
+	misplaced.Transactions = []Transaction{hogEx, spend}
+	if err := misplaced.validateMweb(); err == nil || !strings.Contains(err.Error(), "must be last") {
+		t.Errorf("HogEx before the last transaction accepted: %v", err)
+	}
+
+	twice := *template
+	twice.Transactions = []Transaction{hogEx, hogEx}
+	if err := twice.validateMweb(); err == nil {
+		t.Error("two HogEx transactions accepted")
+	}
+
+	missing := *template
+	missing.Transactions = []Transaction{spend}
+	if err := missing.validateMweb(); err == nil {
+		t.Error("mweb block without a HogEx accepted")
+	}
+
+	orphaned := *template
+	orphaned.MimbleWimble = ""
+	if err := orphaned.validateMweb(); err == nil {
+		t.Error("HogEx without an mweb block accepted")
+	}
+}
+
+func TestMwebSubmission(t *testing.T) {
+	template := loadMwebTemplate(t)
+	block, _, err := GenerateWork(template, nil, "litecoin", mwebArbitrary, mwebPoolScript, mwebReservation, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = block.MakeHeader(mwebExtranonce, mwebNonce, mwebNonceTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submission, err := block.Submit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden, err := os.ReadFile("testdata/mweb-submission.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submission != strings.TrimSpace(string(golden)) {
+		t.Fatalf("submission differs from testdata/mweb-submission.hex:\n%v", submission)
+	}
+
+	// The HogEx comes last, then the extension block as a present optional
+	tail := template.Transactions[1].Data + "01" + template.MimbleWimble
+	if !strings.HasSuffix(submission, tail) {
+		t.Error("submission doesn't end with the HogEx and 01 + mweb")
+	}
+}
+
+func TestPolicyKeepsMwebTransactions(t *testing.T) {
+	template := loadMwebTemplate(t)
+	if template.Transactions[0].isMwebPegIn() {
+		t.Error("segwit spend detected as a peg-in")
+	}
+
+	// A peg-in spending the segwit spend pins its parent too
+	pegIn := template.Transactions[1]
+	pegIn.ID = "pegin"
+	pegIn.Data = "0200000001" + strings.Repeat("00", 36) + "00ffffffff01" + "0000000000000000" +
+		"225920" + strings.Repeat("11", 32) + "00000000"
+	pegIn.Depends = []uint{1}
+	template.Transactions = []Transaction{template.Transactions[0], pegIn, template.Transactions[1]}
+
+	err := template.ApplyTransactionPolicy(TransactionPolicy{EmptyBlocks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(template.Transactions) != 3 {
+		t.Fatalf("kept %v of 3 transactions, the peg-in, its parent and the HogEx are pinned", len(template.Transactions))
+	}
+}
+
+func TestNextEmptyMwebTemplate(t *testing.T) {
+	template := loadMwebTemplate(t)
+	golden, err := os.ReadFile("testdata/mweb-submission.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The golden block becomes the tip the empty job builds on
+	tip, err := ParseMwebTip(strings.TrimSpace(string(golden)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousHogEx, _, _ := readTransaction(mustDecodeHex(t, template.Transactions[1].Data))
+	if tip.hogExID != previousHogEx.ID() || tip.hogExValue != previousHogEx.Outputs[0].Value {
+		t.Fatal("tip HogEx doesn't match the block's")
+	}
+
+	next, err := template.NextEmptyTemplate(Litecoin{}, "aa", tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Height != template.Height+1 || next.CoinBaseValue != 625000000 {
+		t.Errorf("height %v with coinbase value %v", next.Height, next.CoinBaseValue)
+	}
+	if len(next.Transactions) != 1 || !next.Transactions[0].IsHogEx() {
+		t.Fatal("empty MWEB template must hold only the HogEx")
+	}
+	if err = next.validateMweb(); err != nil {
+		t.Fatal(err)
+	}
+	if err = next.validateWitnessCommitment(); err != nil {
+		t.Fatal(err)
+	}
+
+	hogEx, _, err := readTransaction(mustDecodeHex(t, next.Transactions[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hogEx.Outputs[0].Value != tip.hogExValue {
+		t.Error("HogEx must carry the MWEB value over")
+	}
+	header, err := readMwebHeader(mustDecodeHex(t, next.MimbleWimble))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := header.hash()
+	commitment, _ := mwebHeaderCommitment(hogEx.Outputs[0].Script)
+	if header.Height != tip.header.Height+1 || commitment != hash {
+		t.Error("HogEx doesn't commit to the next extension block header")
+	}
+
+	retarget := *template
+	retarget.Height = 2016*1340 - 1
+	if _, err = retarget.NextEmptyTemplate(Litecoin{}, "aa", tip); err == nil {
+		t.Error("empty job built across a retarget")
+	}
+	if _, err = template.NextEmptyTemplate(Dogecoin{}, "aa", tip); err == nil {
+		t.Error("empty job built for a chain retargeting every block")
+	}
+}
+
+// A mainnet block, captured with `litecoin-cli getblock <hash> 0`, is taken apart and
+// rebuilt through the template, merkle, witness commitment and submission code, and its
+// extension block header must hash to what its HogEx commits to.
+func TestMainnetMwebBlock(t *testing.T) {
+	raw, err := os.ReadFile("testdata/mweb-mainnet-block.hex")
+	if os.IsNotExist(err) {
+		t.Skip("no testdata/mweb-mainnet-block.hex, capture one with litecoin-cli getblock <hash> 0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockHex := strings.TrimSpace(string(raw))
+	block := mustDecodeHex(t, blockHex)
+
+	header := block[:80]
+	count, size, err := readVarUint(block[80:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := 80 + size
+
+	var coinbase []byte
+	var coinbaseID [32]byte
+	template := &Template{}
+	for i := uint64(0); i < count; i++ {
+		parsed, length, err := readTransaction(block[position:])
+		if err != nil {
+			t.Fatalf("transaction %v: %v", i, err)
+		}
+		data := block[position : position+length]
+		position += length
+
+		id := parsed.ID()
+		if i == 0 {
+			coinbase, coinbaseID = data, id
+			for _, output := range parsed.Outputs {
+				if strings.HasPrefix(hex.EncodeToString(output.Script), hex.EncodeToString(witnessCommitmentScriptPrefix)) {
+					template.DefaultWitnessCommitment = hex.EncodeToString(output.Script)
+				}
+			}
+			continue
+		}
+
+		transaction := Transaction{Data: hex.EncodeToString(data), ID: hex.EncodeToString(reverse(id[:]))}
+		if !parsed.HogEx && len(data) != len(parsed.Stripped) {
+			witnessID := doubleSha256Bytes(data)
+			transaction.Hash = hex.EncodeToString(reverse(witnessID[:]))
+		}
+		template.Transactions = append(template.Transactions, transaction)
+	}
+	if block[position] != 0x01 {
+		t.Fatal("block has no extension block")
+	}
+	template.MimbleWimble = hex.EncodeToString(block[position+1:])
+
+	if !template.Transactions[len(template.Transactions)-1].IsHogEx() {
+		t.Error("last transaction isn't detected as the HogEx")
+	}
+	if err = template.validateMweb(); err != nil {
+		t.Error(err)
+	}
+	if err = template.validateWitnessCommitment(); err != nil {
+		t.Error(err)
+	}
+
+	steps, err := template.MerkleSteps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := makeHeaderMerkleRoot(coinbaseID, steps)
+	if hex.EncodeToString(root[:]) != hex.EncodeToString(header[36:68]) {
+		t.Error("merkle root differs from the block header's")
+	}
+
+	submission := Submission{
+		Header:           header,
+		TransactionCount: uint(count),
+		Coinbase:         coinbase,
+		Transactions:     template.Transactions,
+		MwebBlock:        template.mwebBlockSerialized(),
+	}
+	if submission.Serialize() != blockHex {
+		t.Error("rebuilt submission differs from the block")
+	}
+
+	if _, err = ParseMwebTip(blockHex); err != nil {
+		t.Errorf("extension block header doesn't match the HogEx commitment: %v", err)
+	}
+}
+
+func mustDecodeHex(t *testing.T, data string) []byte {
+	t.Helper()
+	decoded, err := hex.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
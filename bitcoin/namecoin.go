@@ -0,0 +1,52 @@
+package bitcoin
+
+import (
+	"regexp"
+)
+
+type Namecoin struct{}
+
+func (Namecoin) ChainName() string {
+	return "namecoin"
+}
+
+func (Namecoin) Algorithm() string {
+	return AlgorithmSha256d
+}
+
+func (Namecoin) CoinbaseDigest(coinbase []byte) ([]byte, error) {
+	return DoubleSha256(coinbase)
+}
+
+func (Namecoin) HeaderDigest(header []byte) ([]byte, error) {
+	return DoubleSha256(header)
+}
+
+// Stratum difficulty 1 is the SHA256d difficulty 1 target
+func (Namecoin) ShareMultiplier() float64 {
+	return 1
+}
+
+func (Namecoin) ValidMainnetAddress(address string) bool {
+	return regexp.MustCompile("^(N|M|6)[a-km-zA-HJ-NP-Z1-9]{33}$|^nc1[02-9ac-hj-np-z]{11,71}$").MatchString(address)
+}
+
+func (Namecoin) ValidTestnetAddress(address string) bool {
+	return regexp.MustCompile("^(m|n|2)[a-km-zA-HJ-NP-Z1-9]{33}$|^(tn1|ncrt1)[02-9ac-hj-np-z]{11,71}$").MatchString(address)
+}
+
+func (Namecoin) MinimumConfirmations() uint {
+	return uint(BitcoinMinConfirmations)
+}
+
+func (Namecoin) RetargetInterval() uint {
+	return 2016
+}
+
+func (Namecoin) HalvingInterval() uint {
+	return 210000
+}
+
+func (Namecoin) InitialSubsidy() uint {
+	return 50 * 100000000
+}
@@ -0,0 +1,199 @@
+package bitcoin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// The hex string pipeline the byte slice one replaced, kept here to check the
+// new coinbase, merkle branch and header against it job for job.
+
+func legacyVarUint(value uint) string {
+	var buffer []byte
+	if value <= 252 {
+		buffer = []byte{byte(value)}
+	} else {
+		buffer = binary.LittleEndian.AppendUint16([]byte{0xfd}, uint16(value))
+	}
+	return hex.EncodeToString(buffer)
+}
+
+func legacyReverseHex(hexString string) string {
+	o := ""
+	for i := len(hexString); i > 0; i = i - 2 {
+		o = o + hexString[i-2:i]
+	}
+	return o
+}
+
+func legacyReverseHex4Bytes(hexString string) string {
+	var o string
+	for l, i := len(hexString), 0; i < l/8; i++ {
+		o = o + hexString[l-8*(i+1):(l-(8*i))]
+	}
+	return o
+}
+
+// Only right for heights without a zero byte inside and a clear top bit, as the old code was
+func legacyHeightHex(height uint) string {
+	buffer := binary.LittleEndian.AppendUint64(nil, uint64(height))
+	var cleaned []byte
+	significant := false
+	for _, b := range buffer {
+		if significant && b == 0 {
+			continue
+		}
+		cleaned = append(cleaned, b)
+		if b != 0 {
+			significant = true
+		}
+	}
+	return hex.EncodeToString(cleaned)
+}
+
+func legacyCoinbaseInitial(t *Template, arbitraryByteLength uint) string {
+	heightHex := legacyHeightHex(t.Height)
+	heightByteLen := uint(len(heightHex) / 2)
+	arbitraryByteLength = arbitraryByteLength + heightByteLen + 1
+
+	return "01000000" + "01" +
+		"0000000000000000000000000000000000000000000000000000000000000000" +
+		"ffffffff" +
+		legacyVarUint(arbitraryByteLength) +
+		legacyVarUint(heightByteLen) +
+		heightHex
+}
+
+func legacyCoinbaseFinal(t *Template, poolPubScriptKey string) string {
+	rewardAmount := legacyReverseHex(fmt.Sprintf("%016x", t.CoinBaseValue))
+	output := rewardAmount + legacyVarUint(uint(len(poolPubScriptKey)/2)) + poolPubScriptKey
+	return "00000000" + legacyVarUint(1) + output + "00000000"
+}
+
+func legacyJoin(t *testing.T, one, two string) string {
+	merged := doubleSha256Bytes(append(mustDecodeHex(t, one), mustDecodeHex(t, two)...))
+	return hex.EncodeToString(merged[:])
+}
+
+func legacyMerkleSteps(t *testing.T, template *Template) []string {
+	steps := []string{}
+	if len(template.Transactions) == 0 {
+		return steps
+	}
+
+	level := []string{""}
+	for _, transaction := range template.Transactions {
+		level = append(level, legacyReverseHex(transaction.ID))
+	}
+	for len(level) > 1 {
+		steps = append(steps, level[1])
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		joins := []string{""}
+		for i := 2; i < len(level); i += 2 {
+			joins = append(joins, legacyJoin(t, level[i], level[i+1]))
+		}
+		level = joins
+	}
+	return steps
+}
+
+func legacyMerkleRoot(t *testing.T, coinbaseHashed string, steps []string) string {
+	root := coinbaseHashed
+	for _, step := range steps {
+		root = legacyJoin(t, root, step)
+	}
+	return root
+}
+
+func legacyHeader(t *testing.T, template *Template, merkleRoot, nonceTime, nonce string) string {
+	header := binary.LittleEndian.AppendUint32(nil, uint32(template.Version))
+	header = append(header, reverse(mustDecodeHex(t, template.PrevBlockHash))...)
+	header = append(header, mustDecodeHex(t, merkleRoot)...)
+	header = append(header, reverse(mustDecodeHex(t, nonceTime))...)
+	header = append(header, reverse(mustDecodeHex(t, template.Bits))...)
+	header = append(header, reverse(mustDecodeHex(t, nonce))...)
+	return hex.EncodeToString(header)
+}
+
+func pipelineTemplate(height uint, transactionCount int) *Template {
+	template := &Template{
+		Version:       0x20000000,
+		PrevBlockHash: "7a1f3c0d8e2b4a6c9d0e1f2a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e",
+		Height:        height,
+		CoinBaseValue: 625000000,
+		Bits:          "1a01a9e7",
+		CurrentTime:   0x66672c80,
+	}
+	for i := 0; i < transactionCount; i++ {
+		id := sha256.Sum256([]byte{byte(i)})
+		template.Transactions = append(template.Transactions, Transaction{ID: hex.EncodeToString(id[:])})
+	}
+	return template
+}
+
+func TestBytePipelineMatchesHexPipeline(t *testing.T) {
+	const (
+		poolScript  = "76a9143b8e8b5c0f6a2c1d9b7e4f3a2d1c0b9a8f7e6d5c88ac"
+		arbitrary   = "/dogepool/"
+		reservation = 8
+		extranonce  = "0000000100000000"
+		nonceTime   = "66672c80"
+		nonce       = "1a2b3c4d"
+	)
+
+	for _, height := range []uint{100, 2700001} {
+		for _, transactionCount := range []int{0, 1, 2, 3, 4, 7, 12} {
+			name := fmt.Sprintf("height %v, %v transactions", height, transactionCount)
+			template := pipelineTemplate(height, transactionCount)
+
+			block, work, err := GenerateWork(template, nil, "litecoin", arbitrary, poolScript, reservation, nil)
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+
+			arbitraryHex := hex.EncodeToString(append([]byte{byte(len(arbitrary))}, arbitrary...))
+			coinbaseInitial := legacyCoinbaseInitial(template, uint(len(arbitrary)+1+reservation))
+			coinbaseFinal := arbitraryHex + legacyCoinbaseFinal(template, poolScript)
+			steps := legacyMerkleSteps(t, template)
+
+			if work[1] != legacyReverseHex4Bytes(template.PrevBlockHash) {
+				t.Errorf("%v: previous block hash %v", name, work[1])
+			}
+			if work[2] != coinbaseInitial {
+				t.Errorf("%v: coinbase initial\n%v\n%v", name, work[2], coinbaseInitial)
+			}
+			if work[3] != coinbaseFinal {
+				t.Errorf("%v: coinbase final\n%v\n%v", name, work[3], coinbaseFinal)
+			}
+			if fmt.Sprint(work[4]) != fmt.Sprint(steps) {
+				t.Errorf("%v: merkle steps\n%v\n%v", name, work[4], steps)
+			}
+
+			err = block.MakeHeader(extranonce, nonce, nonceTime)
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+
+			coinbase := mustDecodeHex(t, coinbaseInitial+extranonce+coinbaseFinal)
+			coinbaseHashed := doubleSha256Bytes(coinbase)
+			header := legacyHeader(t, template, legacyMerkleRoot(t, hex.EncodeToString(coinbaseHashed[:]), steps), nonceTime, nonce)
+			if block.HeaderHex() != header {
+				t.Errorf("%v: header\n%v\n%v", name, block.HeaderHex(), header)
+			}
+
+			headerHashed := doubleSha256Bytes(mustDecodeHex(t, header))
+			hashed, err := block.HeaderHashed()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hashed != legacyReverseHex(hex.EncodeToString(headerHashed[:])) {
+				t.Errorf("%v: header hash %v", name, hashed)
+			}
+		}
+	}
+}
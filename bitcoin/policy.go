@@ -0,0 +1,202 @@
+package bitcoin
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Room left in the block for the coinbase, which isn't part of the template
+const coinbaseWeightReservation = 4000
+
+type TransactionPolicy struct {
+	MaxWeight       uint
+	ExcludeTxIDs    map[string]bool
+	ExcludePatterns []*regexp.Regexp
+	MinFeeRate      float64 // Satoshis per virtual byte
+	EmptyBlocks     bool
+}
+
+func MakeTransactionPolicy(maxWeight uint, excludeTxIDs, excludePatterns []string, minFeeRate float64, emptyBlocks bool) (TransactionPolicy, error) {
+	policy := TransactionPolicy{
+		MaxWeight:    maxWeight,
+		ExcludeTxIDs: make(map[string]bool),
+		MinFeeRate:   minFeeRate,
+		EmptyBlocks:  emptyBlocks,
+	}
+
+	for _, txID := range excludeTxIDs {
+		policy.ExcludeTxIDs[strings.ToLower(txID)] = true
+	}
+
+	for _, pattern := range excludePatterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return policy, fmt.Errorf("invalid transaction exclude pattern %v: %v", pattern, err)
+		}
+		policy.ExcludePatterns = append(policy.ExcludePatterns, compiled)
+	}
+
+	return policy, nil
+}
+
+func (p TransactionPolicy) isEmpty() bool {
+	return p.MaxWeight == 0 && len(p.ExcludeTxIDs) == 0 && len(p.ExcludePatterns) == 0 &&
+		p.MinFeeRate == 0 && !p.EmptyBlocks
+}
+
+func (p TransactionPolicy) rejects(transaction Transaction) string {
+	if p.EmptyBlocks {
+		return "empty block mode"
+	}
+	if p.ExcludeTxIDs[strings.ToLower(transaction.ID)] {
+		return "excluded txid"
+	}
+	for _, pattern := range p.ExcludePatterns {
+		if pattern.MatchString(transaction.Data) {
+			return "excluded pattern " + pattern.String()
+		}
+	}
+	if p.MinFeeRate > 0 && transaction.feeRate() < p.MinFeeRate {
+		return fmt.Sprintf("feerate %.2f below %.2f", transaction.feeRate(), p.MinFeeRate)
+	}
+	return ""
+}
+
+// Pre segwit nodes don't report weight
+func (t Transaction) weight() uint {
+	if t.Weight > 0 {
+		return t.Weight
+	}
+	return uint(len(t.Data)/2) * 4
+}
+
+func (t Transaction) feeRate() float64 {
+	virtualSize := (t.weight() + 3) / 4
+	if virtualSize == 0 {
+		return 0
+	}
+	return float64(t.Fee) / float64(virtualSize)
+}
+
+// MWEB peg-ins pay to a witness v9 program (OP_9 PUSH32), which the HogEx spends
+func (t Transaction) isMwebPegIn() bool {
+	scripts, err := t.outputScripts()
+	if err != nil {
+		return false
+	}
+	for _, script := range scripts {
+		if len(script) == 34 && script[0] == 0x59 && script[1] == 0x20 {
+			return true
+		}
+	}
+	return false
+}
+
+// The HogEx and the peg-ins it spends can't be dropped without rebuilding the extension block,
+// and neither can anything they depend on.  Returns the pinned template indexes (1 based).
+func (t *Template) pinnedTransactions() map[uint]bool {
+	pinned := make(map[uint]bool)
+	if !t.HasMweb() {
+		return pinned
+	}
+
+	var pin func(index uint)
+	pin = func(index uint) {
+		if index < 1 || index > uint(len(t.Transactions)) || pinned[index] {
+			return
+		}
+		pinned[index] = true
+		for _, depends := range t.Transactions[index-1].Depends {
+			pin(depends)
+		}
+	}
+
+	for i, transaction := range t.Transactions {
+		if transaction.IsHogEx() || transaction.isMwebPegIn() {
+			pin(uint(i + 1))
+		}
+	}
+	return pinned
+}
+
+// Drops the transactions the policy rejects along with everything that depends on them,
+// then takes their fees out of the coinbase value and recommits the witnesses.
+func (t *Template) ApplyTransactionPolicy(policy TransactionPolicy) error {
+	if policy.isEmpty() {
+		return nil
+	}
+
+	weightLimit := policy.MaxWeight
+	if weightLimit > coinbaseWeightReservation {
+		weightLimit -= coinbaseWeightReservation
+	}
+
+	pinned := t.pinnedTransactions()
+	kept := make([]Transaction, 0, len(t.Transactions))
+	newIndexes := make(map[uint]uint) // Template index (1 based) => kept index (1 based)
+	weight := uint(0)
+	removedFees := 0
+
+	for i, transaction := range t.Transactions {
+		templateIndex := uint(i + 1)
+
+		reason := policy.rejects(transaction)
+		if reason != "" && pinned[templateIndex] {
+			log.Printf("Keeping MWEB transaction %v despite policy: %v", transaction.ID, reason)
+			reason = ""
+		}
+		if reason == "" {
+			for _, depends := range transaction.Depends {
+				if _, exists := newIndexes[depends]; !exists {
+					reason = fmt.Sprintf("depends on dropped transaction %v", depends)
+					break
+				}
+			}
+		}
+		if reason == "" && !pinned[templateIndex] && policy.MaxWeight > 0 && weight+transaction.weight() > weightLimit {
+			reason = fmt.Sprintf("over max weight %v", policy.MaxWeight)
+		}
+
+		if reason != "" {
+			removedFees += transaction.Fee
+			continue
+		}
+
+		keptDepends := make([]uint, 0, len(transaction.Depends))
+		for _, depends := range transaction.Depends {
+			keptDepends = append(keptDepends, newIndexes[depends])
+		}
+		transaction.Depends = keptDepends
+
+		kept = append(kept, transaction)
+		newIndexes[templateIndex] = uint(len(kept))
+		weight += transaction.weight()
+	}
+
+	removed := len(t.Transactions) - len(kept)
+	if removed == 0 {
+		return nil
+	}
+
+	if uint(removedFees) > t.CoinBaseValue {
+		return fmt.Errorf("dropped fees %v exceed the coinbase value %v for height %v", removedFees, t.CoinBaseValue, t.Height)
+	}
+
+	t.Transactions = kept
+	t.CoinBaseValue -= uint(removedFees)
+
+	if t.HasWitnessCommitment() {
+		commitment, err := t.WitnessCommitment()
+		if err != nil {
+			return err
+		}
+		t.DefaultWitnessCommitment = commitment
+	}
+
+	log.Printf("Transaction policy dropped %v of %v transaction(s) and %v in fees for height %v",
+		removed, removed+len(kept), removedFees, t.Height)
+
+	return nil
+}
@@ -1,54 +1,78 @@
 package bitcoin
 
-import "fmt"
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
 
 type Submission struct {
-	Header            string
-	TransactionCount  string
-	Coinbase          string
-	TransactionBuffer string
+	Header           []byte
+	TransactionCount uint
+	Coinbase         []byte
+	Transactions     []Transaction
+	MwebBlock        string
 }
 
 // https://developer.bitcoin.org/reference/block_chain.html#serialized-blocks
 // https://en.bitcoin.it/wiki/BIP_0022#Appendix:_Example_Rejection_Reasons
 
+// Template transactions arrive as hex and submitblock wants hex, so they're never decoded
 func (s *Submission) Serialize() string {
-	return s.Header +
-		s.TransactionCount +
-		s.Coinbase +
-		s.TransactionBuffer
-}
+	var submission strings.Builder
 
-func (b *BitcoinBlock) createSubmissionHex() string {
-	transactionCount := uint(len(b.Template.Transactions) + 1)
+	head := append([]byte{}, s.Header...)
+	head = appendVarUint(head, uint64(s.TransactionCount))
+	head = append(head, s.Coinbase...)
 
-	submission := Submission{
-		Header:            b.Header,    // Changed from b.header to b.Header
-		TransactionCount:  varUint(transactionCount),
-		Coinbase:          b.Coinbase,  // Changed from b.coinbase to b.Coinbase
-		TransactionBuffer: b.buildTransactionBuffer(),
+	length := hex.EncodedLen(len(head)) + len(s.MwebBlock)
+	for _, transaction := range s.Transactions {
+		length += len(transaction.Data)
 	}
+	submission.Grow(length)
 
-	return submission.Serialize()
+	submission.WriteString(hex.EncodeToString(head))
+	for _, transaction := range s.Transactions {
+		submission.WriteString(transaction.Data)
+	}
+	submission.WriteString(s.MwebBlock)
+
+	return submission.String()
 }
 
-func (b *BitcoinBlock) buildTransactionBuffer() string {
-	buffer := ""
-	for _, transaction := range b.Template.Transactions {
-		buffer = buffer + transaction.Data
+func (b *BitcoinBlock) createSubmissionHex() (string, error) {
+	transactionCount := uint(len(b.Template.Transactions) + 1)
+
+	coinbase := b.coinbase
+	if b.Template.HasWitnessCommitment() {
+		var err error
+		coinbase, err = witnessCoinbase(b.coinbase)
+		if err != nil {
+			return "", err
+		}
 	}
-	return buffer
+
+	submission := Submission{
+		Header:           b.header[:],
+		TransactionCount: transactionCount,
+		Coinbase:         coinbase,
+		Transactions:     b.Template.Transactions,
+		MwebBlock:        b.Template.mwebBlockSerialized(),
+	}
+
+	return submission.Serialize(), nil
 }
 
-func submissionDebugOutput(header, transactionCount, coinbase, transactionBuffer, submission string) {
+func submissionDebugOutput(submission Submission) {
 	fmt.Println()
 	fmt.Println("**😱SUBMISSION PARTS😱**")
 	fmt.Println()
-	fmt.Println("Header", header)
-	fmt.Println("TransactionCount", transactionCount)
-	fmt.Println("Coinbase", coinbase)
-	fmt.Println("TransactionBuffer", transactionBuffer)
+	fmt.Println("Header", hex.EncodeToString(submission.Header))
+	fmt.Println("TransactionCount", submission.TransactionCount)
+	fmt.Println("Coinbase", hex.EncodeToString(submission.Coinbase))
+	fmt.Println("Transactions", len(submission.Transactions))
+	fmt.Println("MwebBlock", submission.MwebBlock)
 	fmt.Println()
-	fmt.Println("Submission", submission)
+	fmt.Println("Submission", submission.Serialize())
 	fmt.Println()
 }
@@ -0,0 +1,40 @@
+package bitcoin
+
+import (
+	"regexp"
+)
+
+type Syscoin struct{}
+
+func (Syscoin) ChainName() string {
+	return "syscoin"
+}
+
+func (Syscoin) Algorithm() string {
+	return AlgorithmSha256d
+}
+
+func (Syscoin) CoinbaseDigest(coinbase []byte) ([]byte, error) {
+	return DoubleSha256(coinbase)
+}
+
+func (Syscoin) HeaderDigest(header []byte) ([]byte, error) {
+	return DoubleSha256(header)
+}
+
+// Stratum difficulty 1 is the SHA256d difficulty 1 target
+func (Syscoin) ShareMultiplier() float64 {
+	return 1
+}
+
+func (Syscoin) ValidMainnetAddress(address string) bool {
+	return regexp.MustCompile("^(S|3)[a-km-zA-HJ-NP-Z1-9]{33}$|^sys1[02-9ac-hj-np-z]{11,71}$").MatchString(address)
+}
+
+func (Syscoin) ValidTestnetAddress(address string) bool {
+	return regexp.MustCompile("^(T|2)[a-km-zA-HJ-NP-Z1-9]{33}$|^(tsys1|scrt1)[02-9ac-hj-np-z]{11,71}$").MatchString(address)
+}
+
+func (Syscoin) MinimumConfirmations() uint {
+	return uint(BitcoinMinConfirmations)
+}
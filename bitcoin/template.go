@@ -1,9 +1,25 @@
 package bitcoin
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 type Transaction struct {
-	Data string `json:"data"`
-	ID   string `json:"txid"`
-	Fee  int    `json:"fee"`
+	Data    string `json:"data"`
+	ID      string `json:"txid"`
+	Hash    string `json:"hash"` // wtxid on segwit chains
+	Fee     int    `json:"fee"`
+	Weight  uint   `json:"weight"`
+	Depends []uint `json:"depends"` // 1 based indexes of earlier template transactions
+}
+
+func (t Transaction) WitnessID() string {
+	if t.Hash == "" {
+		return t.ID
+	}
+	return t.Hash
 }
 
 type Template struct {
@@ -16,5 +32,69 @@ type Template struct {
 	Target                   `json:"target"`
 	Transactions             []Transaction `json:"transactions"`
 	CurrentTime              uint          `json:"curtime"`
+	MinTime                  uint          `json:"mintime"`
 	MimbleWimble             string        `json:"mweb"`
 }
+
+// The next height with no transactions.  Bits carry over and the subsidy is worked out from
+// the chain's schedule, so heights where either changes are refused, as are chains without a
+// fixed schedule.  MWEB chains need the new tip's HogEx and extension block header to build on.
+// The full template replaces it within a few seconds either way.
+func (t *Template) NextEmptyTemplate(chain Blockchain, previousBlockHash string, mwebTip *MwebTip) (*Template, error) {
+	scheduled, ok := chain.(ScheduledChain)
+	if !ok {
+		return nil, errors.New(chain.ChainName() + " has no fixed retarget and halving schedule")
+	}
+
+	height := t.Height + 1
+	if height%scheduled.RetargetInterval() == 0 {
+		return nil, fmt.Errorf("height %v is a difficulty retarget", height)
+	}
+	if height%scheduled.HalvingInterval() == 0 {
+		return nil, fmt.Errorf("height %v is a halving", height)
+	}
+
+	halvings := height / scheduled.HalvingInterval()
+	subsidy := uint(0)
+	if halvings < 64 {
+		subsidy = scheduled.InitialSubsidy() >> halvings
+	}
+
+	next := *t
+	next.PrevBlockHash = previousBlockHash
+	next.Height = height
+	next.CoinBaseValue = subsidy
+	next.Transactions = nil
+	next.MimbleWimble = ""
+
+	if t.HasMweb() {
+		if mwebTip == nil {
+			return nil, errors.New("MWEB blocks need the previous block's MWEB state")
+		}
+		if mwebTip.header.Height != uint64(t.Height) {
+			m := "previous block has MWEB height %v, expected %v"
+			return nil, fmt.Errorf(m, mwebTip.header.Height, t.Height)
+		}
+		hogEx, extensionBlock, err := mwebTip.nextEmpty()
+		if err != nil {
+			return nil, err
+		}
+		next.Transactions = []Transaction{hogEx}
+		next.MimbleWimble = extensionBlock
+	}
+
+	now := uint(time.Now().Unix())
+	if now > next.CurrentTime {
+		next.CurrentTime = now
+	}
+
+	if next.HasWitnessCommitment() {
+		commitment, err := next.WitnessCommitment()
+		if err != nil {
+			return nil, err
+		}
+		next.DefaultWitnessCommitment = commitment
+	}
+
+	return &next, nil
+}
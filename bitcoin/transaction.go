@@ -1,6 +1,162 @@
 package bitcoin
 
-func TransactionOut(amount, pubScriptKey string) string {
-	lengthBytes := uint(len(pubScriptKey) / 2)
-	return amount + varUint(lengthBytes) + pubScriptKey
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+func TransactionOut(buffer []byte, amount uint64, pubScriptKey []byte) []byte {
+	buffer = binary.LittleEndian.AppendUint64(buffer, amount)
+	buffer = appendVarUint(buffer, uint64(len(pubScriptKey)))
+	return append(buffer, pubScriptKey...)
+}
+
+type transactionOutput struct {
+	Value  uint64
+	Script []byte
+}
+
+// A transaction read from a block or template
+type parsedTransaction struct {
+	Stripped []byte // Without witnesses or MWEB data, what the txid hashes
+	Outputs  []transactionOutput
+	HogEx    bool // MWEB flagged with no MWEB transaction, see LIP-0003
+}
+
+func (t parsedTransaction) ID() [32]byte {
+	return doubleSha256Bytes(t.Stripped)
+}
+
+// The output scripts of a serialized transaction, with or without witness or MWEB flags
+func (t Transaction) outputScripts() ([][]byte, error) {
+	data, err := hex.DecodeString(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	parsed, _, err := readTransaction(data)
+	if err != nil {
+		return nil, errors.Join(errors.New("transaction "+t.ID), err)
+	}
+	scripts := make([][]byte, len(parsed.Outputs))
+	for i, output := range parsed.Outputs {
+		scripts[i] = output.Script
+	}
+	return scripts, nil
+}
+
+// Reads one serialized transaction from the front of data, returns it and its length
+func readTransaction(data []byte) (parsedTransaction, int, error) {
+	var parsed parsedTransaction
+	truncated := errors.New("truncated transaction")
+	position := 0
+
+	take := func(length int) ([]byte, error) {
+		if length < 0 || len(data)-position < length {
+			return nil, truncated
+		}
+		taken := data[position : position+length]
+		position += length
+		return taken, nil
+	}
+	takeVarUint := func() (uint64, []byte, error) {
+		value, size, err := readVarUint(data[position:])
+		if err != nil {
+			return 0, nil, err
+		}
+		taken, err := take(size)
+		return value, taken, err
+	}
+
+	version, err := take(4)
+	if err != nil {
+		return parsed, 0, err
+	}
+	parsed.Stripped = append(parsed.Stripped, version...)
+
+	flags := byte(0)
+	if len(data) > position+1 && data[position] == 0 && data[position+1] != 0 { // Extended marker and flags
+		flags = data[position+1]
+		position += 2
+	}
+
+	inputs, raw, err := takeVarUint()
+	if err != nil {
+		return parsed, 0, err
+	}
+	parsed.Stripped = append(parsed.Stripped, raw...)
+	for i := uint64(0); i < inputs; i++ {
+		start := position
+		if _, err = take(36); err != nil { // Previous output
+			return parsed, 0, err
+		}
+		length, _, err := takeVarUint()
+		if err != nil {
+			return parsed, 0, err
+		}
+		if _, err = take(int(length) + 4); err != nil { // Script and sequence
+			return parsed, 0, err
+		}
+		parsed.Stripped = append(parsed.Stripped, data[start:position]...)
+	}
+
+	outputs, raw, err := takeVarUint()
+	if err != nil {
+		return parsed, 0, err
+	}
+	parsed.Stripped = append(parsed.Stripped, raw...)
+	for i := uint64(0); i < outputs; i++ {
+		start := position
+		value, err := take(8)
+		if err != nil {
+			return parsed, 0, err
+		}
+		length, _, err := takeVarUint()
+		if err != nil {
+			return parsed, 0, err
+		}
+		script, err := take(int(length))
+		if err != nil {
+			return parsed, 0, err
+		}
+		parsed.Outputs = append(parsed.Outputs, transactionOutput{binary.LittleEndian.Uint64(value), script})
+		parsed.Stripped = append(parsed.Stripped, data[start:position]...)
+	}
+
+	if flags&0x01 != 0 {
+		for i := uint64(0); i < inputs; i++ {
+			items, _, err := takeVarUint()
+			if err != nil {
+				return parsed, 0, err
+			}
+			for j := uint64(0); j < items; j++ {
+				length, _, err := takeVarUint()
+				if err != nil {
+					return parsed, 0, err
+				}
+				if _, err = take(int(length)); err != nil {
+					return parsed, 0, err
+				}
+			}
+		}
+	}
+
+	if flags&mwebTransactionFlag != 0 {
+		present, err := take(1)
+		if err != nil {
+			return parsed, 0, err
+		}
+		if present[0] != 0 {
+			return parsed, 0, errors.New("MWEB transactions outside the extension block aren't supported")
+		}
+		parsed.HogEx = true
+	}
+
+	lockTime, err := take(4)
+	if err != nil {
+		return parsed, 0, err
+	}
+	parsed.Stripped = append(parsed.Stripped, lockTime...)
+
+	return parsed, position, nil
 }
@@ -0,0 +1,91 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki#commitment-structure
+// https://github.com/bitcoin/bips/blob/master/bip-0144.mediawiki#serialization
+
+var (
+	witnessCommitmentScriptPrefix = []byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed} // OP_RETURN, push 36, commitment header
+	witnessMarkerAndFlag          = []byte{0x00, 0x01}
+	witnessReservedValue          = [32]byte{}
+)
+
+func (t *Template) HasWitnessCommitment() bool {
+	return t.DefaultWitnessCommitment != ""
+}
+
+// The coinbase wtxid is always zero, so it sits at the front of the tree like the coinbase does for txids
+func (t *Template) witnessMerkleRoot() ([32]byte, error) {
+	witnessIDs := make([][32]byte, len(t.Transactions))
+	for i, transaction := range t.Transactions {
+		err := decodeHexInto(witnessIDs[i][:], transaction.WitnessID())
+		if err != nil {
+			return [32]byte{}, err
+		}
+		reverseInPlace(witnessIDs[i][:])
+	}
+
+	steps := templateMerkleBranchSteps(witnessIDs)
+
+	return makeHeaderMerkleRoot(witnessReservedValue, steps), nil
+}
+
+func (t *Template) WitnessCommitment() (string, error) {
+	root, err := t.witnessMerkleRoot()
+	if err != nil {
+		return "", err
+	}
+
+	commitment := join(root, witnessReservedValue)
+	script := append(append([]byte{}, witnessCommitmentScriptPrefix...), commitment[:]...)
+
+	return hex.EncodeToString(script), nil
+}
+
+func (t *Template) validateWitnessCommitment() error {
+	if !t.HasWitnessCommitment() {
+		return nil
+	}
+
+	commitment, err := t.WitnessCommitment()
+	if err != nil {
+		return err
+	}
+
+	if commitment != t.DefaultWitnessCommitment {
+		m := "witness commitment mismatch for height %v: (template) %v <> (calculated) %v"
+		m = fmt.Sprintf(m, t.Height, t.DefaultWitnessCommitment, commitment)
+		return errors.New(m)
+	}
+
+	return nil
+}
+
+// Miners hash the stripped coinbase; the block carries it with the witness reserved value
+func witnessCoinbase(coinbase []byte) ([]byte, error) {
+	versionLength, lockTimeLength := 4, 4
+	if len(coinbase) < versionLength+lockTimeLength {
+		return nil, errors.New("coinbase too short to serialize with witness")
+	}
+
+	version := coinbase[:versionLength]
+	inputsAndOutputs := coinbase[versionLength : len(coinbase)-lockTimeLength]
+	lockTime := coinbase[len(coinbase)-lockTimeLength:]
+
+	witness := appendVarUint(nil, 1)
+	witness = appendPushData(witness, witnessReservedValue[:])
+
+	serialized := make([]byte, 0, len(coinbase)+len(witnessMarkerAndFlag)+len(witness))
+	serialized = append(serialized, version...)
+	serialized = append(serialized, witnessMarkerAndFlag...)
+	serialized = append(serialized, inputsAndOutputs...)
+	serialized = append(serialized, witness...)
+	serialized = append(serialized, lockTime...)
+
+	return serialized, nil
+}
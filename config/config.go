@@ -7,17 +7,22 @@ import (
 	"os"
 )
 
-type coinNodeConfig struct {
+type CoinNodeConfig struct {
 	Name         string `json:"name"`
 	RPC_URL      string `json:"rpc_url"`
 	RPC_Username string `json:"rpc_username"`
 	RPC_Password string `json:"rpc_password"`
-	Timeout      string `json:"timeout"`
-	NotifyURL    string `json:"block_notify_url"`
-	RewardTo     string `json:"reward_to"`
+	// Alternatives to a plaintext rpc_password, the cookie file takes precedence
+	RPC_CookieFile   string `json:"rpc_cookie_file"`
+	RPC_UsernameEnv  string `json:"rpc_username_env"`
+	RPC_PasswordEnv  string `json:"rpc_password_env"`
+	RPC_PasswordFile string `json:"rpc_password_file"`
+	Timeout          string `json:"timeout"`
+	NotifyURL        string `json:"block_notify_url"`
+	RewardTo         string `json:"reward_to"`
 }
 
-type blockChainNodesConfigMap map[string][]coinNodeConfig // coin name => [] of blockNodes
+type blockChainNodesConfigMap map[string][]CoinNodeConfig // coin name => [] of blockNodes
 
 type BlockChainOrder []string
 
@@ -55,6 +60,34 @@ type Chain struct {
 	RewardFrom           string      `json:"reward_from"`
 	MinerMinimumPayment  float32     `json:"miner_min_payment"`
 	PoolRewardRecipients []recipient `json:"pool_rewards"`
+
+	// Primary chain only: pay pool_rewards as coinbase outputs instead of crediting balances
+	PoolRewardsInCoinbase bool `json:"pool_rewards_in_coinbase"`
+
+	// Primary chain only: split the coinbase over the PPLNS window instead of crediting balances,
+	// pool_rewards are then always paid in the coinbase
+	CoinbasePayouts       bool    `json:"coinbase_payouts"`
+	CoinbaseDustThreshold float64 `json:"coinbase_dust_threshold"` // Coins
+	CoinbaseMaxOutputs    int     `json:"coinbase_max_outputs"`
+
+	// Encrypted wallets are unlocked just for sending payouts, never keep the passphrase in this file
+	WalletPassphraseEnv  string `json:"wallet_passphrase_env"`
+	WalletPassphraseFile string `json:"wallet_passphrase_file"`
+	WalletUnlockWindow   string `json:"wallet_unlock_window"` // Defaults to 30s
+
+	// Build unsigned payout transactions for offline signing instead of sending from the wallet
+	OfflineSigning bool `json:"offline_signing"`
+
+	// Payout transactions are tracked until confirmed, and paid again when conflicted or
+	// left unconfirmed for too long
+	PaymentConfirmations int64  `json:"payment_confirmations"` // Defaults to 6
+	PaymentDroppedAfter  string `json:"payment_dropped_after"` // Defaults to 24h
+
+	// Payout batching and fees, zero values leave each unlimited or to the wallet
+	PayoutMaxRecipients int     `json:"payout_max_recipients"` // Larger payouts are split over several transactions
+	PayoutMaxTotal      float64 `json:"payout_max_total"`      // Coins per payout run, the rest waits for the next run
+	PayoutFeeRate       float64 `json:"payout_fee_rate"`       // sat/vB
+	PayoutSubtractFee   bool    `json:"payout_subtract_fee"`   // Recipients share the network fee
 }
 
 type Chains map[string]Chain // chainName => chain payout config
@@ -74,14 +107,56 @@ type VarDiffConfig struct {
 	VariancePercent float64 `json:"variance_percent"`
 }
 
+// Zero values fall back to one worker per CPU, a queue of four shares per worker,
+// a 2s share timeout and a 1m metrics log interval
+type HasherConfig struct {
+	Workers         int    `json:"workers"`
+	QueueSize       int    `json:"queue_size"`
+	Timeout         string `json:"timeout"`
+	MetricsInterval string `json:"metrics_interval"`
+}
+
+// Applied to every primary chain template before work is generated
+type TransactionPolicyConfig struct {
+	MaxWeight       uint     `json:"max_weight"`
+	ExcludeTxIDs    []string `json:"exclude_txids"`
+	ExcludePatterns []string `json:"exclude_patterns"` // Regular expressions matched against raw transaction hex
+	MinFeeRate      float64  `json:"min_feerate"`      // Satoshis per virtual byte
+	EmptyBlocks     bool     `json:"empty_blocks"`
+}
+
+// BIP23 proposals of every new template, and of the current one every interval
+type TemplateProposalConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Interval string `json:"interval"`
+}
+
+// A reachable node failing any of these is failed over from.  Unset fields take their defaults,
+// a min_peers of 0 or a negative max_header_lag disables that check.
+type NodeHealthConfig struct {
+	MinPeers      *int64 `json:"min_peers"`      // Defaults to 1
+	MaxTipAge     string `json:"max_tip_age"`    // Disabled by default
+	MaxHeaderLag  *int64 `json:"max_header_lag"` // Blocks behind the best header, defaults to 2
+	RequireWallet bool   `json:"require_wallet"`
+}
+
 type Config struct {
-	PoolName           string                   `json:"pool_name"`
-	BlockSignature     string                   `json:"block_signature"`
-	BlockchainNodes    blockChainNodesConfigMap `json:"blockchains"`
-	Port               string                   `json:"port"`
-	MaxConnections     int                      `json:"max_connections"`
-	ConnectionTimeout  string                   `json:"connection_timeout"`
-	VarDiff            VarDiffConfig            `json:"vardiff"`
+	PoolName           string                    `json:"pool_name"`
+	BlockSignature     string                    `json:"block_signature"`
+	BlockchainNodes    blockChainNodesConfigMap  `json:"blockchains"`
+	WalletNodes        map[string]CoinNodeConfig `json:"wallet_nodes"` // Optional per chain, else payouts use the active template node
+	Port               string                    `json:"port"`
+	MaxConnections     int                       `json:"max_connections"`
+	Extranonce2Size    int                       `json:"extranonce2_size"`    // Bytes, defaults to 4
+	NtimeFutureWindow  string                    `json:"ntime_future_window"` // Defaults to 2h
+	ConnectionTimeout  string                    `json:"connection_timeout"`
+	VarDiff            VarDiffConfig             `json:"vardiff"`
+	Hasher             HasherConfig              `json:"hasher"`
+	TransactionPolicy  TransactionPolicyConfig   `json:"transaction_policy"`
+	TemplateProposals  TemplateProposalConfig    `json:"template_proposals"`
+	RPCHealthInterval  string                    `json:"rpc_health_interval"` // Defaults to 30s
+	NodeHealth         NodeHealthConfig          `json:"node_health"`
+	ReturnToPrimary    string                    `json:"rpc_return_to_primary"` // Defaults to 1h
 	BlockChainOrder    `json:"merged_blockchain_order"`
 	ShareFlushInterval string        `json:"share_flush_interval"`
 	HashrateWindow     string        `json:"hashrate_window"`
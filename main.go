@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"designs.capital/dogepool/api"
+	"designs.capital/dogepool/bitcoin"
 	"designs.capital/dogepool/config"
 	"designs.capital/dogepool/payouts"
 	"designs.capital/dogepool/persistence"
@@ -27,12 +32,63 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *listPayoutBatches || *importPayoutBatch != 0 || *cancelPayoutBatch != 0 {
+		runPayoutBatchCommand(configuration)
+		return
+	}
+
 	rpcManagers := makeRPCManagers(configuration)
 	startPoolServer(configuration, rpcManagers)
 	startStatManager(configuration)
-	startAPIServer(configuration)
+	startAPIServer(configuration, rpcManagers)
 	startPayoutService(configuration, rpcManagers)
-	startAppStatsService(configuration)
+	startAppStatsService(configuration, rpcManagers)
+}
+
+// Offline payout signing, see payouts/offline.go
+var (
+	listPayoutBatches = flag.Bool("list-payout-batches", false, "print the unsigned payout batches as JSON and exit")
+	importPayoutBatch = flag.Uint("import-payout-batch", 0, "broadcast the signed transaction of this payout batch and exit")
+	signedTransaction = flag.String("signed", "", "file holding the signed PSBT or raw transaction for -import-payout-batch")
+	cancelPayoutBatch = flag.Uint("cancel-payout-batch", 0, "give an unsigned payout batch's amounts back to its balances and exit")
+)
+
+func runPayoutBatchCommand(configuration *config.Config) {
+	if *listPayoutBatches {
+		batches, err := persistence.PayoutBatches.GetByStatus(configuration.PoolName, persistence.BatchStatusUnsigned)
+		if err != nil {
+			log.Fatal(err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(batches)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	rpcManagers := makeRPCManagers(configuration)
+
+	if *cancelPayoutBatch != 0 {
+		err := payouts.CancelUnsignedBatch(context.Background(), configuration, rpcManagers, *cancelPayoutBatch)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Payout batch %v cancelled", *cancelPayoutBatch)
+		return
+	}
+
+	signed, err := os.ReadFile(*signedTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transactionID, err := payouts.ImportSignedBatch(context.Background(), configuration, rpcManagers,
+		*importPayoutBatch, strings.TrimSpace(string(signed)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Payout batch %v broadcast: %v", *importPayoutBatch, transactionID)
 }
 
 func parseCommandLineOptions() string {
@@ -47,8 +103,8 @@ func startPoolServer(configuration *config.Config, managers map[string]*rpc.Mana
 	return poolServer
 }
 
-func startAPIServer(configuration *config.Config) {
-	go api.ListenAndServe(configuration)
+func startAPIServer(configuration *config.Config, managers map[string]*rpc.Manager) {
+	go api.ListenAndServe(configuration, managers)
 	log.Println("Started API on port: " + configuration.API.Port)
 }
 
@@ -65,7 +121,7 @@ func startPayoutService(configuration *config.Config, manager map[string]*rpc.Ma
 	log.Printf("Payouts manager running every %v\n", interval)
 }
 
-func startAppStatsService(configuration *config.Config) {
+func startAppStatsService(configuration *config.Config, managers map[string]*rpc.Manager) {
 	interval := mustParseDuration(configuration.AppStatsInterval)
 	for {
 		var memStats runtime.MemStats
@@ -75,6 +131,14 @@ func startAppStatsService(configuration *config.Config) {
 		log.Printf("Total Goroutines: %v", runtime.NumGoroutine())
 		log.Printf("Total System Memory: %v", memStats.Sys)
 		log.Printf("Total Memory Allocated: %v", memStats.TotalAlloc)
+		for chain, manager := range managers {
+			for _, client := range manager.GetClients() {
+				for method, stats := range client.Metrics() {
+					m := "RPC %v %v %v: %v calls, %v errors, %v average"
+					log.Printf(m, chain, client.Name, method, stats.Calls, stats.Errors, stats.AverageLatency())
+				}
+			}
+		}
 		fmt.Println("STATS END")
 		time.Sleep(interval)
 	}
@@ -82,25 +146,65 @@ func startAppStatsService(configuration *config.Config) {
 
 func makeRPCManagers(configuration *config.Config) map[string]*rpc.Manager {
 	managers := make(map[string]*rpc.Manager)
+	healthInterval := configuration.RPCHealthInterval
+	if healthInterval == "" {
+		healthInterval = "30s"
+	}
+	returnToPrimary := configuration.ReturnToPrimary
+	if returnToPrimary == "" {
+		returnToPrimary = "1h"
+	}
+	thresholds := rpc.HealthThresholds{
+		// Never mine on a node that can't relay our blocks
+		MinPeers: 1,
+		// Headers arrive before their blocks, a node validating a new block isn't behind
+		MaxHeaderLag:  2,
+		RequireWallet: configuration.NodeHealth.RequireWallet,
+	}
+	if configuration.NodeHealth.MinPeers != nil {
+		thresholds.MinPeers = *configuration.NodeHealth.MinPeers
+	}
+	if configuration.NodeHealth.MaxHeaderLag != nil {
+		thresholds.MaxHeaderLag = *configuration.NodeHealth.MaxHeaderLag
+	}
+	if configuration.NodeHealth.MaxTipAge != "" {
+		thresholds.MaxTipAge = mustParseDuration(configuration.NodeHealth.MaxTipAge)
+	}
 	for _, chain := range configuration.BlockChainOrder {
 		nodeConfigs := configuration.BlockchainNodes[chain]
 		rpcConfig := make([]rpc.Config, len(nodeConfigs))
 		for i, nodeConfig := range nodeConfigs {
-			rpcConfig[i] = rpc.Config{
-				Name:     nodeConfig.Name,
-				URL:      nodeConfig.RPC_URL,
-				Username: nodeConfig.RPC_Username,
-				Password: nodeConfig.RPC_Password,
-				Timeout:  nodeConfig.Timeout,
-			}
+			rpcConfig[i] = makeRPCConfig(chain, nodeConfig)
 		}
-		// TODO move interval to config if accepted
-		manager := rpc.MakeRPCManager(chain, rpcConfig, "1h")
-		managers[chain] = &manager
+		manager := rpc.MakeRPCManager(chain, rpcConfig, thresholds, healthInterval, returnToPrimary)
+		if walletConfig, exists := configuration.WalletNodes[chain]; exists {
+			manager.SetWalletClient(rpc.NewRPCClient(makeRPCConfig(chain, walletConfig)))
+		}
+		manager.Start()
+		managers[chain] = manager
 	}
 	return managers
 }
 
+func makeRPCConfig(chain string, nodeConfig config.CoinNodeConfig) rpc.Config {
+	rpcConfig := rpc.Config{
+		Name:     nodeConfig.Name,
+		URL:      nodeConfig.RPC_URL,
+		Username: nodeConfig.RPC_Username,
+		Password: nodeConfig.RPC_Password,
+		Timeout:  nodeConfig.Timeout,
+
+		CookieFile:   nodeConfig.RPC_CookieFile,
+		UsernameEnv:  nodeConfig.RPC_UsernameEnv,
+		PasswordEnv:  nodeConfig.RPC_PasswordEnv,
+		PasswordFile: nodeConfig.RPC_PasswordFile,
+	}
+	if multiAlgorithm, ok := bitcoin.GetChain(chain).(bitcoin.MultiAlgorithmChain); ok {
+		rpcConfig.Algorithm = multiAlgorithm.TemplateAlgorithm()
+	}
+	return rpcConfig
+}
+
 func mustParseDuration(s string) time.Duration {
 	value, err := time.ParseDuration(s)
 	if err != nil {
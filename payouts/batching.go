@@ -0,0 +1,97 @@
+package payouts
+
+import (
+	"context"
+	"log"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+)
+
+// Holds back the balances that don't fit under payout_max_total until the next run
+func limitPayoutTotal(chain string, balances []persistence.Balance, maxTotal float64) []persistence.Balance {
+	if maxTotal <= 0 {
+		return balances
+	}
+
+	var total float64
+	var limited []persistence.Balance
+	for _, balance := range balances {
+		if total+balance.Amount > maxTotal {
+			continue
+		}
+		total += balance.Amount
+		limited = append(limited, balance)
+	}
+
+	if held := len(balances) - len(limited); held > 0 {
+		log.Printf("%v payouts capped at %v, %v balances held for the next run\n", chain, maxTotal, held)
+	}
+	return limited
+}
+
+// Splits balances into batches paying at most maxRecipients addresses each.
+// Balances paying the same address always share a batch.
+func splitPayoutBalances(balances []persistence.Balance, config *config.Config, maxRecipients int) ([][]persistence.Balance, error) {
+	if len(balances) < 1 {
+		return nil, nil
+	}
+	if maxRecipients <= 0 {
+		return [][]persistence.Balance{balances}, nil
+	}
+
+	var batches [][]persistence.Balance
+	batchOf := make(map[string]int) // address => batch index
+	recipients := 0
+	for _, balance := range balances {
+		address, err := findBalanceAddress(balance, config)
+		if err != nil {
+			return nil, err
+		}
+
+		if i, found := batchOf[address]; found {
+			batches[i] = append(batches[i], balance)
+			continue
+		}
+		if len(batches) == 0 || recipients == maxRecipients {
+			batches = append(batches, nil)
+			recipients = 0
+		}
+		last := len(batches) - 1
+		batches[last] = append(batches[last], balance)
+		batchOf[address] = last
+		recipients++
+	}
+
+	return batches, nil
+}
+
+func payoutFees(payoutConfig config.Chain) rpc.FeeOptions {
+	return rpc.FeeOptions{
+		FeeRate:     payoutConfig.PayoutFeeRate,
+		SubtractFee: payoutConfig.PayoutSubtractFee,
+	}
+}
+
+// Legacy wallets' sendmany takes no fee rate, the wallet's own is set first instead.
+// The returned func puts the wallet's previous rate back once the payout is sent.
+func setLegacyWalletFee(ctx context.Context, chain string, node *rpc.RPCClient, fees rpc.FeeOptions) (rpc.FeeOptions, func(), error) {
+	if _, legacy := bitcoin.GetChain(chain).(bitcoin.LegacyWalletChain); !legacy || fees.FeeRate <= 0 {
+		return fees, func() {}, nil
+	}
+	previous, err := node.SetTransactionFee(ctx, fees)
+	if err != nil {
+		return fees, nil, err
+	}
+	restore := func() {
+		// Restored even when the payout was cancelled
+		err := node.RestoreTransactionFee(context.WithoutCancel(ctx), previous)
+		if err != nil {
+			log.Printf("⚠️  Can't restore the %v wallet's fee rate of %v: %v\n", chain, previous, err)
+		}
+	}
+	fees.FeeRate = 0
+	return fees, restore, nil
+}
@@ -0,0 +1,63 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+)
+
+// Tags the wallet transaction so a batch can be matched after a crash
+func payoutBatchComment(id uint) string {
+	return fmt.Sprintf("payout batch %v", id)
+}
+
+// Settles reserved batches left by a crash or a failed send.  Only the wallet that sent a
+// batch can say it never went out, so its balances are released only on that wallet's answer.
+// Otherwise the batch stays reserved and payouts are held until the operator settles it.
+func reconcilePayoutBatches(ctx context.Context, config *config.Config, rpcManagers map[string]*rpc.Manager) error {
+	batches, err := persistence.PayoutBatches.GetByStatus(config.PoolName, persistence.BatchStatusReserved)
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range batches {
+		rpcManager, exists := rpcManagers[batch.Chain]
+		if !exists {
+			return errors.New("payouts.reconcilePayoutBatches() - failed to find chain rpc: " + batch.Chain)
+		}
+
+		wallet := rpcManager.GetClientByURL(batch.WalletNode)
+		if wallet == nil {
+			m := "⚠️  can't reconcile %v payout batch %v, the wallet node that sent it is unknown, holding payouts"
+			return fmt.Errorf(m, batch.Chain, batch.ID)
+		}
+
+		// Clocks of the pool and node may differ a little
+		since := batch.Created.Add(-time.Hour)
+		comment := payoutBatchComment(batch.ID)
+		transactionID, found, err := wallet.FindSentTransaction(ctx, comment, since)
+		if err != nil {
+			m := "⚠️  can't reconcile %v payout batch %v, holding payouts"
+			return errors.Join(fmt.Errorf(m, batch.Chain, batch.ID), err)
+		}
+
+		if found {
+			log.Printf("%v payout batch %v was sent as %v, recording it\n", batch.Chain, batch.ID, transactionID)
+			err = markBatchBroadcast(ctx, wallet, batch, transactionID)
+		} else {
+			log.Printf("%v payout batch %v was never sent, releasing its balances\n", batch.Chain, batch.ID)
+			err = persistence.PayoutBatches.Cancel(batch)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
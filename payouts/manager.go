@@ -1,6 +1,7 @@
 package payouts
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -15,11 +16,12 @@ func RunManager(config *config.Config, rpcManagers map[string]*rpc.Manager, inte
 	var cutoffTime time.Time
 	for {
 		time.Sleep(interval)
+		ctx := context.Background()
 
 		log.Println("Checking block confirmations")
 
 		// Unlock Loop
-		blocks, err = unlockBlocks(config.PoolName, rpcManagers)
+		blocks, err = unlockBlocks(ctx, config.PoolName, rpcManagers)
 		if err != nil {
 			log.Println(err)
 			continue
@@ -27,12 +29,18 @@ func RunManager(config *config.Config, rpcManagers map[string]*rpc.Manager, inte
 
 		// Calculate Rewards loop
 		for _, confirmed := range blocks.GetConfirmed() {
-			rpcManager, exists := rpcManagers[confirmed.Chain]
-			if !exists {
-				panic("payouts.Manager: Blockchain not found - " + confirmed.Chain)
-			}
+			if confirmed.PaidInCoinbase {
+				log.Printf("%v block %v paid its miners in the coinbase, nothing to credit\n", confirmed.Chain, confirmed.BlockHeight)
+				// Its window was paid from the template, only the shares before it can go
+				_, cutoffTime, err = pplnsWindowFractions(config.PoolName, confirmed.Created)
+			} else {
+				rpcManager, exists := rpcManagers[confirmed.Chain]
+				if !exists {
+					panic("payouts.Manager: Blockchain not found - " + confirmed.Chain)
+				}
 
-			cutoffTime, err = calculateBlockRewards(confirmed, config, rpcManager)
+				cutoffTime, err = calculateBlockRewards(confirmed, config, rpcManager)
+			}
 			if err != nil {
 				log.Println(err)
 				continue
@@ -54,7 +62,7 @@ func RunManager(config *config.Config, rpcManagers map[string]*rpc.Manager, inte
 		}
 
 		// Actual payouts
-		err = payoutBalances(config, rpcManagers)
+		err = payoutBalances(ctx, config, rpcManagers)
 		if err != nil {
 			log.Println(err)
 			continue
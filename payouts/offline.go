@@ -0,0 +1,205 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+)
+
+const (
+	rpcInvalidAddressOrKey  = -5  // getrawtransaction's code for a transaction the node doesn't know
+	rpcVerifyAlreadyInChain = -27 // sendrawtransaction's code for a transaction already mined
+)
+
+// Builds an unsigned payout transaction for the treasury to sign offline.
+// Its balances stay reserved until the signed transaction is imported, or the batch cancelled.
+// Nothing is built while an earlier batch awaits signature, see payoutBalances.
+func createOfflineBatch(ctx context.Context, config *config.Config, chain string, balances []persistence.Balance, rpcManager *rpc.Manager) error {
+	if len(balances) < 1 {
+		return nil
+	}
+
+	items, outputs, err := makePayoutBatchItems(balances, config)
+	if err != nil {
+		return err
+	}
+
+	node := rpcManager.GetWalletClient()
+	fees := payoutFees(config.Payouts.Chains[chain])
+	format := bitcoin.UnsignedTransactionFormat(chain)
+	var unsigned string
+	if format == bitcoin.UnsignedFormatPSBT {
+		unsigned, err = node.CreateFundedPSBT(ctx, outputs, fees)
+	} else {
+		unsigned, err = node.CreateFundedRawTransaction(ctx, outputs, fees)
+	}
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to build unsigned %v payouts", chain), err)
+	}
+
+	id, err := persistence.PayoutBatches.Insert(persistence.PayoutBatch{
+		PoolID:              config.PoolName,
+		Chain:               chain,
+		Status:              persistence.BatchStatusUnsigned,
+		Format:              format,
+		UnsignedTransaction: unsigned,
+		WalletNode:          node.NodeUrl, // Holds the locks on the inputs
+		Items:               items,
+		Created:             time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%v payout batch %v of %v payments awaiting offline signing\n", chain, id, len(items))
+	return nil
+}
+
+// Broadcasts the treasury's signed PSBT or raw transaction for a batch, then records its payments
+func ImportSignedBatch(ctx context.Context, config *config.Config, rpcManagers map[string]*rpc.Manager, id uint, signed string) (string, error) {
+	batch, err := persistence.PayoutBatches.Get(config.PoolName, id)
+	if err != nil {
+		return "", err
+	}
+	if batch.Status != persistence.BatchStatusUnsigned {
+		return "", fmt.Errorf("payout batch %v is already %v", id, batch.Status)
+	}
+
+	rpcManager, exists := rpcManagers[batch.Chain]
+	if !exists {
+		return "", errors.New("payouts.ImportSignedBatch() - failed to find chain rpc: " + batch.Chain)
+	}
+	node, err := batchWallet(rpcManager, batch)
+	if err != nil {
+		return "", err
+	}
+
+	transactionHex := signed
+	if batch.Format == bitcoin.UnsignedFormatPSBT {
+		transactionHex, err = node.FinalizePSBT(ctx, signed)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	decoded, err := verifyBatchOutputs(ctx, node, batch, transactionHex)
+	if err != nil {
+		return "", err
+	}
+
+	// Known before broadcasting, a send lost on the way may still have reached the mempool
+	transactionID := decoded.TransactionID
+	_, err = node.SendRawTransaction(ctx, transactionHex)
+	var rpcError *rpc.Error
+	if errors.As(err, &rpcError) && rpcError.Code != rpcVerifyAlreadyInChain {
+		// The node refused, nothing was sent
+		return "", err
+	}
+	if err != nil && rpcError == nil {
+		// Tracking settles it, a transaction that never went out is dropped and re-credited
+		log.Printf("⚠️  %v payout batch %v may not have been broadcast as %v: %v\n", batch.Chain, id, transactionID, err)
+	} else {
+		log.Printf("%v payout batch %v broadcast: %v\n", batch.Chain, id, transactionID)
+	}
+
+	batch.Items = paidItems(batch.Items, decoded.Outputs)
+	err = persistence.PayoutBatches.MarkBroadcast(*batch, transactionID)
+	if err != nil {
+		m := "⚠️  payout batch %v was broadcast as %v but could not be marked, finalise it by hand"
+		return transactionID, errors.Join(fmt.Errorf(m, id, transactionID), err)
+	}
+
+	return transactionID, nil
+}
+
+// Gives an unsigned batch's reserved amounts back to its balances and its inputs back to the wallet
+func CancelUnsignedBatch(ctx context.Context, config *config.Config, rpcManagers map[string]*rpc.Manager, id uint) error {
+	batch, err := persistence.PayoutBatches.Get(config.PoolName, id)
+	if err != nil {
+		return err
+	}
+	if batch.Status != persistence.BatchStatusUnsigned {
+		return fmt.Errorf("payout batch %v is %v, only unsigned batches can be cancelled", id, batch.Status)
+	}
+
+	rpcManager, exists := rpcManagers[batch.Chain]
+	if !exists {
+		return errors.New("payouts.CancelUnsignedBatch() - failed to find chain rpc: " + batch.Chain)
+	}
+	node, err := batchWallet(rpcManager, batch)
+	if err != nil {
+		return err
+	}
+	unsigned, err := decodeUnsignedBatch(ctx, node, batch)
+	if err != nil {
+		return err
+	}
+
+	// A restarted node has already dropped the locks, a stale lock only idles the coins until then
+	err = node.UnlockInputs(ctx, unsigned.Inputs)
+	if err != nil {
+		log.Printf("⚠️  payout batch %v inputs could not be unlocked: %v\n", id, err)
+	}
+
+	return persistence.PayoutBatches.Cancel(*batch)
+}
+
+// The wallet that sent a wallet format batch, or funded an offline one and holds the locks on its inputs
+func batchWallet(rpcManager *rpc.Manager, batch *persistence.PayoutBatch) (*rpc.RPCClient, error) {
+	if batch.WalletNode == "" {
+		// Funded before batches recorded their wallet
+		return rpcManager.GetWalletClient(), nil
+	}
+	node := rpcManager.GetClientByURL(batch.WalletNode)
+	if node == nil {
+		return nil, fmt.Errorf("%v payout batch %v was funded by %v, which is no longer configured", batch.Chain, batch.ID, batch.WalletNode)
+	}
+	return node, nil
+}
+
+func decodeUnsignedBatch(ctx context.Context, node *rpc.RPCClient, batch *persistence.PayoutBatch) (rpc.DecodedTransaction, error) {
+	if batch.Format == bitcoin.UnsignedFormatPSBT {
+		return node.DecodePSBT(ctx, batch.UnsignedTransaction)
+	}
+	return node.DecodeRawTransaction(ctx, batch.UnsignedTransaction)
+}
+
+// The signed transaction must pay every address of the batch at least what the unsigned
+// one did, which is its amount less its share of the fee when the fee is subtracted
+func verifyBatchOutputs(ctx context.Context, node *rpc.RPCClient, batch *persistence.PayoutBatch, transactionHex string) (rpc.DecodedTransaction, error) {
+	unsigned, err := decodeUnsignedBatch(ctx, node, batch)
+	if err != nil {
+		return rpc.DecodedTransaction{}, err
+	}
+	signed, err := node.DecodeRawTransaction(ctx, transactionHex)
+	if err != nil {
+		return signed, err
+	}
+
+	expected := paidAddresses(unsigned.Outputs)
+	paid := paidAddresses(signed.Outputs)
+
+	owed := make(map[string]float64)
+	for _, item := range batch.Items {
+		owed[item.Address] += item.Amount
+	}
+
+	const satoshi = 0.00000001
+	for address, amount := range owed {
+		amount = math.Min(amount, expected[address])
+		if paid[address]+satoshi/2 < amount {
+			m := "signed transaction pays %v %v, batch %v owes it %v"
+			return signed, fmt.Errorf(m, address, paid[address], batch.ID, amount)
+		}
+	}
+
+	return signed, nil
+}
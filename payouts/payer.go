@@ -1,9 +1,12 @@
 package payouts
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"os"
 	"strings"
 	"time"
 
@@ -13,104 +16,213 @@ import (
 )
 
 // The coin should handle it's own paying. TODO.
-func payoutBalances(config *config.Config, rpcManagers map[string]*rpc.Manager) error {
-	var balances []persistence.Balance
+func payoutBalances(ctx context.Context, config *config.Config, rpcManagers map[string]*rpc.Manager) error {
+	// Re-credited balances are paid again below
+	err := trackPayoutBatches(ctx, config, rpcManagers)
+	if err != nil {
+		return err
+	}
+
+	// Nothing new goes out while an earlier batch's fate is unknown
+	err = reconcilePayoutBatches(ctx, config, rpcManagers)
+	if err != nil {
+		return err
+	}
+
 	for _, chain := range config.BlockChainOrder {
 		payoutConfig, exists := config.Payouts.Chains[chain]
 		if !exists {
 			return errors.New("payouts.payoutBalances() - failed to find chain payout config: " + chain)
 		}
-		b, err := persistence.Balances.GetPoolBalancesOverThreshold(config.PoolName, chain, payoutConfig.MinerMinimumPayment)
+		rpcManager, exists := rpcManagers[chain]
+		if !exists {
+			return errors.New("payouts.payoutBalances() - failed to find chain rpc: " + chain)
+		}
+		balances, err := persistence.Balances.GetPoolBalancesOverThreshold(config.PoolName, chain, payoutConfig.MinerMinimumPayment)
 		if err != nil {
 			return err
 		}
-		balances = append(balances, b...)
+		balances = limitPayoutTotal(chain, balances, payoutConfig.PayoutMaxTotal)
+
+		if payoutConfig.OfflineSigning && len(balances) > 0 {
+			pending, err := persistence.PayoutBatches.HasUnsigned(config.PoolName, chain)
+			if err != nil {
+				return err
+			}
+			if pending {
+				// The same balances would be paid twice
+				log.Printf("%v payout batch still awaiting signature, not building another\n", chain)
+				continue
+			}
+		}
+
+		batches, err := splitPayoutBalances(balances, config, payoutConfig.PayoutMaxRecipients)
+		if err != nil {
+			return err
+		}
+		for _, batch := range batches {
+			if payoutConfig.OfflineSigning {
+				err = createOfflineBatch(ctx, config, chain, batch, rpcManager)
+			} else {
+				err = sendPayoutBatch(ctx, config, chain, batch, rpcManager)
+			}
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	// Send payments
-	transactionConfirmation, err := bitcoinTryManyPayments(balances, config, rpcManagers)
+	return nil
+}
+
+// TODO move to bitcoin aka the chain package.
+func sendPayoutBatch(ctx context.Context, config *config.Config, chain string, balances []persistence.Balance, rpcManager *rpc.Manager) error {
+	if len(balances) < 1 {
+		return nil
+	}
+
+	items, outputs, err := makePayoutBatchItems(balances, config)
 	if err != nil {
 		return err
 	}
 
-	for _, balance := range balances {
-		confirmation, found := transactionConfirmation[balance.Chain]
-		if !found {
-			m := "failed to find payment confirmation for %v on chain %v"
-			m = fmt.Sprintf(balance.Address, balance.Chain)
-			return errors.New(m)
-		}
+	node := rpcManager.GetWalletClient()
+	payoutConfig := config.Payouts.Chains[chain]
+	fees, restoreFee, err := setLegacyWalletFee(ctx, chain, node, payoutFees(payoutConfig))
+	if err != nil {
+		return err
+	}
+	defer restoreFee()
 
-		// Record Payments
-		address, err := findBalanceAddress(balance, config)
-		if err != nil {
-			return err
-		}
+	// The intent and the balance reservation are committed before anything is sent
+	batch := persistence.PayoutBatch{
+		PoolID:     config.PoolName,
+		Chain:      chain,
+		Status:     persistence.BatchStatusReserved,
+		Format:     persistence.BatchFormatWallet,
+		WalletNode: node.NodeUrl, // Only this wallet can later tell whether the batch went out
+		Items:      items,
+		Created:    time.Now(),
+	}
+	batch.ID, err = persistence.PayoutBatches.Insert(batch)
+	if err != nil {
+		return err
+	}
 
-		err = persistence.Payments.Insert(persistence.Payment{
-			PoolID:                      balance.PoolID,
-			Chain:                       balance.Chain,
-			Address:                     address,
-			Amount:                      balance.Amount,
-			Created:                     time.Now(),
-			TransactionConfirmationData: confirmation,
-		})
+	window := 30 * time.Second
+	if payoutConfig.WalletUnlockWindow != "" {
+		window, err = time.ParseDuration(payoutConfig.WalletUnlockWindow)
 		if err != nil {
 			return err
 		}
+	}
 
-		// Reset Balance
-		usage := "Paid balance to miner"
-		err = persistence.Balances.AddAmount(config.PoolName, balance.Chain, balance.Address, usage, balance.Amount*-1)
-		if err != nil {
-			return err
+	var transactionID string
+	err = node.WithUnlockedWallet(ctx, walletPassphrase(payoutConfig), window, func() error {
+		var sendErr error
+		transactionID, sendErr = node.SendMany(ctx, outputs, payoutBatchComment(batch.ID), fees)
+		return sendErr
+	})
+	if err != nil {
+		m := "failed to send %v payments"
+		m = fmt.Sprintf(m, chain)
+		context := errors.New(m)
+		err = errors.Join(context, err)
+
+		var rpcError *rpc.Error
+		if errors.As(err, &rpcError) || errors.Is(err, rpc.ErrWalletPassphraseMissing) {
+			// The wallet refused, nothing was sent
+			return errors.Join(err, persistence.PayoutBatches.Cancel(batch))
 		}
+		// The send may or may not have happened, reconciliation will tell from the wallet
+		return err
 	}
 
+	log.Printf("%v Payouts Transaction ID: %v\n", chain, transactionID)
+
+	err = markBatchBroadcast(ctx, node, batch, transactionID)
+	if err != nil {
+		// Still reserved, reconciliation records it on the next run
+		m := "⚠️  %v payout batch %v was sent as %v but not recorded yet"
+		return errors.Join(fmt.Errorf(m, chain, batch.ID, transactionID), err)
+	}
 	return nil
 }
 
-// TODO move to bitcoin aka the chain package.
-func bitcoinTryManyPayments(balances []persistence.Balance, config *config.Config, rpcManagers map[string]*rpc.Manager) (map[string]string, error) {
-	transactionsGroupedByChain := make(map[string]map[string]float64)
-	transactionConfirmationByChain := make(map[string]string)
+// Marks a batch the wallet sent broadcast, recording what its transaction actually pays
+func markBatchBroadcast(ctx context.Context, node *rpc.RPCClient, batch persistence.PayoutBatch, transactionID string) error {
+	transaction, err := node.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	decoded, err := node.DecodeRawTransaction(ctx, transaction.Hex)
+	if err != nil {
+		return err
+	}
+	batch.Items = paidItems(batch.Items, decoded.Outputs)
+	return persistence.PayoutBatches.MarkBroadcast(batch, transactionID)
+}
 
-	for _, balance := range balances {
-		chainBalances, exists := transactionsGroupedByChain[balance.Chain]
-		if !exists {
-			chainBalances = make(map[string]float64)
+// With the fee subtracted recipients get less than their balances gave up.  Each item's
+// payment is its share of what its address was paid, never more than its amount.
+func paidItems(items []persistence.PayoutBatchItem, outputs []rpc.DecodedOutput) []persistence.PayoutBatchItem {
+	paid := paidAddresses(outputs)
+	owed := make(map[string]float64)
+	for _, item := range items {
+		owed[item.Address] += item.Amount
+	}
+
+	result := make([]persistence.PayoutBatchItem, len(items))
+	for i, item := range items {
+		result[i] = item
+		if paid[item.Address] < owed[item.Address] {
+			amount := item.Amount * paid[item.Address] / owed[item.Address]
+			result[i].Amount = math.Round(amount*1e8) / 1e8
 		}
+	}
+	return result
+}
+
+func paidAddresses(outputs []rpc.DecodedOutput) map[string]float64 {
+	paid := make(map[string]float64)
+	for _, output := range outputs {
+		paid[output.Address()] += output.Value
+	}
+	return paid
+}
 
+func makePayoutBatchItems(balances []persistence.Balance, config *config.Config) ([]persistence.PayoutBatchItem, map[string]float64, error) {
+	outputs := make(map[string]float64)
+	items := make([]persistence.PayoutBatchItem, len(balances))
+	for i, balance := range balances {
 		address, err := findBalanceAddress(balance, config)
 		if err != nil {
-			return transactionConfirmationByChain, err
+			return nil, nil, err
+		}
+		outputs[address] += balance.Amount
+		items[i] = persistence.PayoutBatchItem{
+			BalanceAddress: balance.Address,
+			Address:        address,
+			Amount:         balance.Amount,
 		}
-
-		chainBalances[address] = balance.Amount
-		transactionsGroupedByChain[balance.Chain] = chainBalances
 	}
+	return items, outputs, nil
+}
 
-	for chain, transactions := range transactionsGroupedByChain {
-		client, exists := rpcManagers[chain]
-		if !exists {
-			return transactionConfirmationByChain, errors.New("payouts.bitcoinTryManyPayments() - failed to find chain rpc: " + chain)
+func walletPassphrase(payoutConfig config.Chain) func() (string, error) {
+	return func() (string, error) {
+		if payoutConfig.WalletPassphraseFile != "" {
+			passphrase, err := os.ReadFile(payoutConfig.WalletPassphraseFile)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(passphrase)), nil
 		}
-		node := client.GetActiveClient()
-		transactionID, err := node.SendMany(transactions)
-		if err != nil {
-			m := "failed to send %v payments"
-			m = fmt.Sprintf(m, chain)
-			context := errors.New(m)
-			err = errors.Join(context, err)
-			return transactionConfirmationByChain, err
+		if payoutConfig.WalletPassphraseEnv != "" {
+			return os.Getenv(payoutConfig.WalletPassphraseEnv), nil
 		}
-
-		transactionConfirmationByChain[chain] = transactionID
-
-		log.Printf("%v Payouts Transaction ID: %v\n", chain, transactionID)
+		return "", nil
 	}
-
-	return transactionConfirmationByChain, nil
 }
 
 // TODO - move this to REWARDS?
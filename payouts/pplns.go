@@ -10,35 +10,73 @@ import (
 	"designs.capital/dogepool/persistence"
 )
 
+// TODO - move this to config
+// PPLNS window (see https://bitcointalk.org/index.php?topic=39832)
+const pplnsWindow = float64(2)
+
 type PPLNS struct {
 	config *config.Config
 }
 
 func (scheme PPLNS) UpdateMinerBalances(poolID string, blockReward float64, confirmed persistence.Found) (time.Time, error) {
-	emptyTime, cutoffTime := time.Time{}, time.Time{}
-	before := confirmed.Created
-	inclusive := true
-	currentPage := 0
-	pageSize := 100000
+	emptyTime := time.Time{}
 
-	// TODO - move this to config
-	// PPLNS window (see https://bitcointalk.org/index.php?topic=39832)
-	NWindow := float64(2)
+	log.Printf("PPLNS Payouts: scoring window for %v block %v\n", confirmed.Chain, confirmed.BlockHeight)
+
+	minerFractions, cutoffTime, err := pplnsWindowFractions(poolID, confirmed.Created)
+	if err != nil {
+		return emptyTime, err
+	}
 
-	done := false
 	remainingReward := blockReward
-	accumlatedScore := float64(0)
 	minerRewards := make(map[string]float64)
-	for !done {
+	for miner, fraction := range minerFractions {
+		reward := fraction * blockReward
+		minerRewards[miner] = reward
+		remainingReward -= reward
+	}
+
+	if remainingReward < 0 {
+		return emptyTime, errors.New("PPLNS payout overflow! - we awarded more than we have.  Awards not persisted")
+	}
+
+	for miner, reward := range minerRewards {
+		log.Printf("Awarding %v %v PPLNS reward to miner %v for work on %v block %v\n",
+			reward, confirmed.Chain, miner, confirmed.Chain, confirmed.BlockHeight)
+
+		usage := "PPLNS REWARD FOR BLOCK %v"
+		usage = fmt.Sprintf(usage, confirmed.BlockHeight)
+		err = persistence.Balances.AddAmount(poolID, confirmed.Chain, miner, usage, reward)
+		if err != nil {
+			context := errors.New("failed to add balances: ")
+			return emptyTime, errors.Join(context, err)
+		}
+	}
+
+	return cutoffTime, nil
+}
+
+// Each miner's fraction of the last N network difficulties of shares up to before.
+// Fractions add up to less than 1 while the window isn't full yet.
+func PPLNSWindowFractions(poolID string, before time.Time) (map[string]float64, error) {
+	fractions, _, err := pplnsWindowFractions(poolID, before)
+	return fractions, err
+}
+
+func pplnsWindowFractions(poolID string, before time.Time) (map[string]float64, time.Time, error) {
+	cutoffTime := time.Time{}
+	inclusive := true
+	pageSize := 100000
+
+	accumlatedScore := float64(0)
+	minerFractions := make(map[string]float64)
+	for accumlatedScore < pplnsWindow {
 		page, err := persistence.Shares.GetSharesBefore(poolID, before, inclusive, pageSize)
 		if err != nil {
-			return emptyTime, err
+			return nil, cutoffTime, err
 		}
 
 		inclusive = false
-		currentPage++
-
-		log.Printf("PPLNS Payouts: paging through page %v of shares for %v block %v\n", currentPage, confirmed.Chain, confirmed.BlockHeight)
 
 		for _, share := range page {
 			// TODO: Adjust share difficulty if coin needs it.
@@ -46,44 +84,26 @@ func (scheme PPLNS) UpdateMinerBalances(poolID string, blockReward float64, conf
 
 			score := adjustedShare / share.NetworkDifficulty
 
-			if accumlatedScore+score >= NWindow {
-				score = NWindow - accumlatedScore
+			if accumlatedScore+score >= pplnsWindow {
+				score = pplnsWindow - accumlatedScore
 				cutoffTime = share.Created
-				done = true
 			}
 
-			reward := score * blockReward / NWindow
-			minerRewards[share.Miner] += reward
-			remainingReward -= reward
+			accumlatedScore += score
+			minerFractions[share.Miner] += score / pplnsWindow
 
-			if remainingReward <= 0 {
-				return emptyTime, errors.New("PPLNS payout overflow! - we awarded more than we have.  Awards not persisted")
+			if accumlatedScore >= pplnsWindow {
+				break
 			}
-
 		}
 
 		pageLength := len(page)
 		if pageLength < pageSize {
-			done = true
 			break
 		}
 
 		before = page[pageLength-1].Created
 	}
 
-	var err error
-	for miner, reward := range minerRewards {
-		log.Printf("Awarding %v %v PPLNS reward to miner %v for work on %v block %v\n",
-			reward, confirmed.Chain, confirmed.Miner, confirmed.Chain, confirmed.BlockHeight)
-
-		usage := "PPLNS REWARD FOR BLOCK %v"
-		usage = fmt.Sprintf(usage, confirmed.BlockHeight)
-		err = persistence.Balances.AddAmount(poolID, confirmed.Chain, miner, usage, reward)
-		if err != nil {
-			context := errors.New("failed to add balances: ")
-			return emptyTime, errors.Join(context, err)
-		}
-	}
-
-	return cutoffTime, nil
+	return minerFractions, cutoffTime, nil
 }
@@ -27,15 +27,20 @@ func calculatePoolReward(confirmed persistence.Found, config *config.Config, rpc
 		return 0, errors.New("calculatePoolReward(): failed to find payout config for: " + confirmed.Chain)
 	}
 
+	chain, exists := config.BlockchainNodes[confirmed.Chain]
+	if !exists {
+		return 0, errors.New("calculatePoolReward(): failed to get blockchain node for : " + confirmed.Chain)
+	}
+	node := chain[rpcManager.GetIndex()]
+
+	if confirmed.PoolRewardsInCoinbase {
+		return poolRewardPaidInCoinbase(confirmed, payoutConfig, node.RewardTo), nil
+	}
+
 	for _, poolRecipient := range payoutConfig.PoolRewardRecipients {
 		recipientAmount := poolRecipient.Percentage * confirmed.Reward
 		remainingReward -= recipientAmount
 
-		chain, exists := config.BlockchainNodes[confirmed.Chain]
-		if !exists {
-			return 0, errors.New("calculatePoolReward(): failed to get blockchain node for : " + confirmed.Chain)
-		}
-		node := chain[rpcManager.GetIndex()]
 		if poolRecipient.Address == node.RewardTo { // The block chain reward address is the same as the pool reward address
 			continue
 		}
@@ -52,6 +57,23 @@ func calculatePoolReward(confirmed persistence.Found, config *config.Config, rpc
 	return remainingReward, nil
 }
 
+// The wallet only received what the coinbase's pool reward outputs left over,
+// so work back to the block's value before taking the pool's own cut
+func poolRewardPaidInCoinbase(confirmed persistence.Found, payoutConfig config.Chain, rewardTo string) float64 {
+	outputsPercentage, totalPercentage := float64(0), float64(0)
+	for _, poolRecipient := range payoutConfig.PoolRewardRecipients {
+		totalPercentage += poolRecipient.Percentage
+		if poolRecipient.Address != rewardTo {
+			outputsPercentage += poolRecipient.Percentage
+		}
+	}
+
+	blockValue := confirmed.Reward / (1 - outputsPercentage)
+	log.Printf("%v block %v paid its pool rewards in the coinbase\n", confirmed.Chain, confirmed.BlockHeight)
+
+	return blockValue * (1 - totalPercentage)
+}
+
 func calculateMinerRewards(remainingReward float64, confirmed persistence.Found, config *config.Config) (time.Time, error) {
 	payoutSchemeName := config.Payouts.Scheme
 	payoutScheme := payoutSchemeFactory(payoutSchemeName, config)
@@ -0,0 +1,213 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+)
+
+// Follows broadcast batches until they confirm, wallet batches through the wallet that sent
+// them and offline ones on the node.  Conflicted batches, and batches that sat unconfirmed
+// past payment_dropped_after and left the mempool, have their balances credited back so the
+// same payout run pays them again.
+func trackPayoutBatches(ctx context.Context, config *config.Config, rpcManagers map[string]*rpc.Manager) error {
+	batches, err := persistence.PayoutBatches.GetByStatus(config.PoolName, persistence.BatchStatusBroadcast)
+	if err != nil {
+		return err
+	}
+
+	// One gettransaction batch per wallet instead of a round trip per payout batch.
+	// Offline batches are signed elsewhere, so they're looked up on the node instead.
+	byWallet := make(map[*rpc.RPCClient][]persistence.PayoutBatch)
+	var offline []persistence.PayoutBatch
+	for _, batch := range batches {
+		rpcManager, exists := rpcManagers[batch.Chain]
+		if !exists {
+			return fmt.Errorf("payouts.trackPayoutBatches() - failed to find chain rpc: %v", batch.Chain)
+		}
+		if batch.Format != persistence.BatchFormatWallet {
+			offline = append(offline, batch)
+			continue
+		}
+		wallet, err := batchWallet(rpcManager, &batch)
+		if err != nil {
+			log.Printf("Can't track %v payout batch %v: %v\n", batch.Chain, batch.ID, err)
+			continue
+		}
+		byWallet[wallet] = append(byWallet[wallet], batch)
+	}
+
+	for wallet, walletBatches := range byWallet {
+		transactionIDs := make([]string, len(walletBatches))
+		for i, batch := range walletBatches {
+			transactionIDs[i] = batch.TransactionID
+		}
+		transactions, errs := wallet.GetTransactions(ctx, transactionIDs)
+
+		for i, batch := range walletBatches {
+			// A tracking failure never risks paying twice, so it doesn't hold the other batches
+			err = errs[i]
+			if err == nil {
+				err = trackPayoutBatch(ctx, config, batch, transactions[i], wallet)
+			}
+			if err != nil {
+				log.Printf("Can't track %v payout batch %v: %v\n", batch.Chain, batch.ID, err)
+			}
+		}
+	}
+
+	for _, batch := range offline {
+		node, err := batchWallet(rpcManagers[batch.Chain], &batch)
+		if err == nil {
+			var transaction rpc.Transaction
+			transaction, err = lookupOfflineTransaction(ctx, node, batch)
+			if err == nil {
+				err = trackPayoutBatch(ctx, config, batch, transaction, node)
+			}
+		}
+		if err != nil {
+			log.Printf("Can't track %v payout batch %v: %v\n", batch.Chain, batch.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func trackPayoutBatch(ctx context.Context, config *config.Config, batch persistence.PayoutBatch, transaction rpc.Transaction, wallet *rpc.RPCClient) error {
+	payoutConfig := config.Payouts.Chains[batch.Chain]
+	required := payoutConfig.PaymentConfirmations
+	if required < 1 {
+		required = 6
+	}
+	droppedAfter := 24 * time.Hour
+	if payoutConfig.PaymentDroppedAfter != "" {
+		var err error
+		droppedAfter, err = time.ParseDuration(payoutConfig.PaymentDroppedAfter)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case transaction.Confirmations >= required:
+		log.Printf("%v payout batch %v confirmed\n", batch.Chain, batch.ID)
+		return persistence.PayoutBatches.MarkConfirmed(batch, transaction.Confirmations)
+	case transaction.ReplacedBy != "":
+		log.Printf("%v payout batch %v was replaced by %v\n", batch.Chain, batch.ID, transaction.ReplacedBy)
+		return persistence.PayoutBatches.Replace(batch, transaction.ReplacedBy)
+	case transaction.Confirmations < 0:
+		log.Printf("%v payout batch %v conflicted, re-crediting its balances\n", batch.Chain, batch.ID)
+		return persistence.PayoutBatches.Recredit(batch, persistence.BatchStatusConflicted, transaction.Confirmations)
+	case transaction.Confirmations == 0 && time.Since(batch.Updated) > droppedAfter:
+		err := releaseDroppedBatch(ctx, wallet, batch, transaction)
+		if err != nil {
+			return err
+		}
+		log.Printf("%v payout batch %v dropped, re-crediting its balances\n", batch.Chain, batch.ID)
+		return persistence.PayoutBatches.Recredit(batch, persistence.BatchStatusDropped, 0)
+	default:
+		return persistence.PayoutBatches.UpdateConfirmations(batch, transaction.Confirmations)
+	}
+}
+
+// Lets the inputs of a dropped batch be spent again. Re-crediting is only safe while they're
+// unspent, otherwise the batch or a replacement may still confirm.
+func releaseDroppedBatch(ctx context.Context, wallet *rpc.RPCClient, batch persistence.PayoutBatch, transaction rpc.Transaction) error {
+	var inputs []rpc.DecodedInput
+	if batch.Format == persistence.BatchFormatWallet {
+		// The wallet refuses while the transaction is still in its mempool, then it's kept waiting
+		err := wallet.AbandonTransaction(ctx, batch.TransactionID)
+		if err != nil {
+			return err
+		}
+		decoded, err := wallet.DecodeRawTransaction(ctx, transaction.Hex)
+		if err != nil {
+			return err
+		}
+		inputs = decoded.Inputs
+	} else {
+		if transaction.Hex != "" {
+			return fmt.Errorf("%v is still in the mempool of %v", batch.TransactionID, wallet.Name)
+		}
+		unsigned, err := decodeUnsignedBatch(ctx, wallet, &batch)
+		if err != nil {
+			return err
+		}
+		inputs = unsigned.Inputs
+	}
+
+	unspent, err := inputsUnspent(ctx, wallet, inputs)
+	if err != nil {
+		return err
+	}
+	if !unspent {
+		return fmt.Errorf("%v left the mempool but its inputs are spent, not re-crediting", batch.TransactionID)
+	}
+
+	if batch.Format != persistence.BatchFormatWallet {
+		// Funding locked them, a restarted node has already let them go
+		err = wallet.UnlockInputs(ctx, inputs)
+		if err != nil {
+			log.Printf("⚠️  %v payout batch %v inputs could not be unlocked: %v\n", batch.Chain, batch.ID, err)
+		}
+	}
+	return nil
+}
+
+// getrawtransaction finds an offline batch while it's in the mempool, or once confirmed with
+// -txindex. Without the index, its unspent outputs tell how deep it is.  Found nowhere with its
+// inputs unspent, it never reached the network or was dropped, and counts as unconfirmed.
+func lookupOfflineTransaction(ctx context.Context, node *rpc.RPCClient, batch persistence.PayoutBatch) (rpc.Transaction, error) {
+	transaction, err := node.GetRawTransaction(ctx, batch.TransactionID)
+	var rpcError *rpc.Error
+	if !errors.As(err, &rpcError) || rpcError.Code != rpcInvalidAddressOrKey {
+		return transaction, err
+	}
+
+	unsigned, err := decodeUnsignedBatch(ctx, node, &batch)
+	if err != nil {
+		return transaction, err
+	}
+	outpoints := make([]rpc.DecodedInput, len(unsigned.Outputs))
+	for i := range outpoints {
+		outpoints[i] = rpc.DecodedInput{TransactionID: batch.TransactionID, Output: uint32(i)}
+	}
+	outputs, errs := node.GetTxOuts(ctx, outpoints)
+	for i, output := range outputs {
+		if errs[i] != nil {
+			return transaction, errs[i]
+		}
+		if output != nil {
+			return rpc.Transaction{TransactionID: batch.TransactionID, Confirmations: output.Confirmations}, nil
+		}
+	}
+
+	unspent, err := inputsUnspent(ctx, node, unsigned.Inputs)
+	if err != nil {
+		return transaction, err
+	}
+	if !unspent {
+		m := "%v isn't known to %v and its inputs are spent, it confirmed and was spent without -txindex to show it, or conflicted"
+		return transaction, fmt.Errorf(m, batch.TransactionID, node.Name)
+	}
+	return rpc.Transaction{TransactionID: batch.TransactionID}, nil
+}
+
+func inputsUnspent(ctx context.Context, node *rpc.RPCClient, inputs []rpc.DecodedInput) (bool, error) {
+	outputs, errs := node.GetTxOuts(ctx, inputs)
+	for i, output := range outputs {
+		if errs[i] != nil {
+			return false, errs[i]
+		}
+		if output == nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
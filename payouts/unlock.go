@@ -1,6 +1,7 @@
 package payouts
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -10,14 +11,14 @@ import (
 	"designs.capital/dogepool/rpc"
 )
 
-func unlockBlocks(poolID string, rpcManager map[string]*rpc.Manager) (persistence.FoundBlocks, error) {
+func unlockBlocks(ctx context.Context, poolID string, rpcManager map[string]*rpc.Manager) (persistence.FoundBlocks, error) {
 	pending, err := persistence.Blocks.PendingBlocksForPool(poolID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get chain based on block type
-	blocks, err := classifyBlocks(pending, rpcManager)
+	blocks, err := classifyBlocks(ctx, pending, rpcManager)
 	if err != nil {
 		return nil, err
 	}
@@ -28,75 +29,108 @@ func unlockBlocks(poolID string, rpcManager map[string]*rpc.Manager) (persistenc
 // TODO - This is very bitcoin/chain specific
 // We eventually have to let the chain package consume the RPC package, and handle all chain related logic there.
 // ^ That will take care of a lot of TODOs related to seperation of concerns
-func classifyBlocks(blocks persistence.FoundBlocks, rpcManagers map[string]*rpc.Manager) (persistence.FoundBlocks, error) {
-	var rpcManager *rpc.Manager
-	var exists bool
+func classifyBlocks(ctx context.Context, blocks persistence.FoundBlocks, rpcManagers map[string]*rpc.Manager) (persistence.FoundBlocks, error) {
+	// One batch of getblock and one of gettransaction per chain instead of a round trip per block
+	blocksByChain := make(map[string][]int)
 	for i, localBlock := range blocks {
-		rpcManager, exists = rpcManagers[localBlock.Chain]
+		blocksByChain[localBlock.Chain] = append(blocksByChain[localBlock.Chain], i)
+	}
+
+	for chain, indexes := range blocksByChain {
+		rpcManager, exists := rpcManagers[chain]
 		if !exists {
-			return nil, errors.New("unlocker failed to find node for: " + localBlock.Chain)
+			return nil, errors.New("unlocker failed to find node for: " + chain)
+		}
+		err := classifyChainBlocks(ctx, blocks, indexes, rpcManager.GetActiveClient(), rpcManager.GetWalletClient())
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		remoteBlock, err := rpcManager.GetActiveClient().GetBlockByHash(localBlock.Hash)
+	return blocks, nil
+}
+
+// Blocks come from any synced node, coinbase transactions only from the wallet holding them
+func classifyChainBlocks(ctx context.Context, blocks persistence.FoundBlocks, indexes []int, node, wallet *rpc.RPCClient) error {
+	hashes := make([]string, len(indexes))
+	for i, index := range indexes {
+		hashes[i] = blocks[index].Hash
+	}
+	remoteBlocks, errs := node.GetBlocksByHash(ctx, hashes)
+
+	transactionIDs := make([]string, len(indexes))
+	for i, index := range indexes {
+		localBlock := &blocks[index]
+		remoteBlock, err := remoteBlocks[i], errs[i]
 		if err != nil {
 			m := "unlocker failed to find remote block for %v block %v, %v"
 			m = fmt.Sprintf(m, localBlock.Chain, localBlock.BlockHeight, localBlock.Hash)
-			context := errors.New(m)
-			err = errors.Join(context, err)
-			return nil, err
+			errContext := errors.New(m)
+			err = errors.Join(errContext, err)
+			return err
 		}
 
 		if len(remoteBlock.Transactions) < 1 {
 			m := "unlocker failed to fetch transaction confirmation for %v block %v, %v"
 			m = fmt.Sprintf(m, localBlock.Chain, localBlock.BlockHeight, localBlock.Hash)
-			return nil, errors.New(m)
+			return errors.New(m)
 		}
 
 		remoteCoinbaseTransactionHash := remoteBlock.Transactions[0]
 		remoteCoinbaseTransactionHash, err = reverseHexBytes(remoteCoinbaseTransactionHash)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if localBlock.TransactionConfirmationData != "" {
 			if localBlock.TransactionConfirmationData != remoteCoinbaseTransactionHash {
 				// Likely an orphan
 				m := "⚠️  Our confirmation data for %v height %v does not match the blockchains: (local) %v <> (remote) %v"
 				m = fmt.Sprintf(m, localBlock.Chain, localBlock.BlockHeight, localBlock.TransactionConfirmationData, remoteCoinbaseTransactionHash)
-				return nil, errors.New(m)
+				return errors.New(m)
 			}
 		} else { // Aux blocks do not return coinbase data
 			localBlock.TransactionConfirmationData = remoteCoinbaseTransactionHash
 		}
 
-		localConfirmationDataLittleEndian, err := reverseHexBytes(localBlock.TransactionConfirmationData)
+		transactionIDs[i], err = reverseHexBytes(localBlock.TransactionConfirmationData)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		coinbaseTransaction, err := rpcManager.GetActiveClient().GetTransaction(localConfirmationDataLittleEndian)
+	}
+
+	coinbaseTransactions, errs := wallet.GetTransactions(ctx, transactionIDs)
+
+	for i, index := range indexes {
+		localBlock := &blocks[index]
+		coinbaseTransaction, err := coinbaseTransactions[i], errs[i]
 		if err != nil {
 			m := "%v Block %v: (confirmation) %v"
 			m = fmt.Sprintf(m, localBlock.Chain, localBlock.BlockHeight, localBlock.TransactionConfirmationData)
-			context := errors.New(m)
-			return nil, errors.Join(context, err)
+			errContext := errors.New(m)
+			return errors.Join(errContext, err)
+		}
+		if len(coinbaseTransaction.Details) < 1 {
+			m := "%v Block %v: coinbase %v has no wallet details"
+			return fmt.Errorf(m, localBlock.Chain, localBlock.BlockHeight, localBlock.TransactionConfirmationData)
 		}
 
 		switch coinbaseTransaction.Details[0].Category {
 		case "immature":
 			min := bitcoin.GetChain(localBlock.Chain).MinimumConfirmations()
-			blocks[i].ConfirmationProgress = float32(coinbaseTransaction.Confirmations) / float32(min)
-			blocks[i].ConfirmationProgress = roundToThreeDigits(blocks[i].ConfirmationProgress)
-			blocks[i].Reward = coinbaseTransaction.Amount
+			localBlock.ConfirmationProgress = float32(coinbaseTransaction.Confirmations) / float32(min)
+			localBlock.ConfirmationProgress = roundToThreeDigits(localBlock.ConfirmationProgress)
+			localBlock.Reward = coinbaseTransaction.Amount
 		case "generate":
-			blocks[i].Status = persistence.StatusConfirmed
-			blocks[i].ConfirmationProgress = 1
-			blocks[i].Reward = coinbaseTransaction.Amount
+			localBlock.Status = persistence.StatusConfirmed
+			localBlock.ConfirmationProgress = 1
+			localBlock.Reward = coinbaseTransaction.Amount
 		default:
-			blocks[i].Status = persistence.StatusOrphaned
-			blocks[i].Reward = 0
+			localBlock.Status = persistence.StatusOrphaned
+			localBlock.Reward = 0
 		}
 	}
 
-	return blocks, nil
+	return nil
 }
 
 func calculateBlockEffort(blocks persistence.FoundBlocks, poolID string) (persistence.FoundBlocks, error) {
@@ -119,15 +119,17 @@ func (r *BalanceRepository) GetBalance(poolID, chain, address string) (*float64,
 	return &balance, nil
 }
 
+// Each miner's own threshold for the chain applies when set, never below the pool minimum
 func (r *BalanceRepository) GetPoolBalancesOverThreshold(poolID, chain string, minimum float32) ([]Balance, error) {
 	query := `SELECT b.poolid, b.chain, b.address, b.amount, b.created, b.updated
 				FROM balances b
 				LEFT JOIN miner_settings ms
 				ON ms.poolid = b.poolid
 				AND ms.address = b.address
+				AND ms.chain = b.chain
 				WHERE b.poolid = $1
 				AND b.chain = $2
-				AND b.amount >= COALESCE(ms.paymentthreshold, $3)`
+				AND b.amount >= GREATEST(ms.paymentthreshold, $3)`
 
 	stmt, err := r.DB.Prepare(query)
 	if err != nil {
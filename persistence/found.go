@@ -29,6 +29,9 @@ type Found struct {
 	Reward                      float64
 	Source                      string
 	Hash                        string
+	PaidInCoinbase              bool   // Miners were paid by the block itself, nothing to credit
+	PoolRewardsInCoinbase       bool   // Pool reward recipients were paid by the block itself
+	Submissions                 string // JSON of each node's submitblock/submitauxblock result
 	Created                     time.Time
 }
 
@@ -49,14 +52,14 @@ type FoundRepository struct {
 }
 
 func (r *FoundRepository) Insert(block Found) error {
-	query := `INSERT INTO blocks(poolid, chain, blockheight, networkdifficulty, status, "type", transactionconfirmationdata, miner, reward, effort, confirmationprogress, source, hash, created)
-	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	query := `INSERT INTO blocks(poolid, chain, blockheight, networkdifficulty, status, "type", transactionconfirmationdata, miner, reward, effort, confirmationprogress, source, hash, paidincoinbase, poolrewardsincoinbase, submissions, created)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
 
 	block.NetworkDifficulty = roundToThreeDigits(block.NetworkDifficulty)
 
 	_, err := r.DB.Exec(query, &block.PoolID, &block.Chain, &block.BlockHeight, &block.NetworkDifficulty,
 		&block.Status, &block.Type, &block.TransactionConfirmationData, &block.Miner,
-		&block.Reward, &block.Effort, &block.ConfirmationProgress, &block.Source, &block.Hash, &block.Created)
+		&block.Reward, &block.Effort, &block.ConfirmationProgress, &block.Source, &block.Hash, &block.PaidInCoinbase, &block.PoolRewardsInCoinbase, &block.Submissions, &block.Created)
 
 	return err
 }
@@ -177,7 +180,8 @@ func (r *FoundRepository) PageBlocksAcrossAllPools(blockStatus uint, page, pageS
 func (r *FoundRepository) PendingBlocksForPool(poolID string) (FoundBlocks, error) {
 	query := `SELECT id, poolid, type, chain, blockheight, networkdifficulty, status,
 					confirmationprogress, effort, transactionconfirmationdata,
-					miner, reward, source, hash, created
+					miner, reward, source, hash, paidincoinbase, poolrewardsincoinbase,
+					COALESCE(submissions, ''), created
 		 		FROM blocks WHERE poolid = $1 AND status = $2`
 
 	stmt, err := r.DB.Prepare(query)
@@ -198,7 +202,7 @@ func (r *FoundRepository) PendingBlocksForPool(poolID string) (FoundBlocks, erro
 			&block.BlockHeight, &block.NetworkDifficulty, &block.Status,
 			&block.ConfirmationProgress, &block.Effort,
 			&block.TransactionConfirmationData, &block.Miner, &block.Reward,
-			&block.Source, &block.Hash, &block.Created)
+			&block.Source, &block.Hash, &block.PaidInCoinbase, &block.PoolRewardsInCoinbase, &block.Submissions, &block.Created)
 		if err != nil {
 			return nil, err
 		}
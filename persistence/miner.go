@@ -14,24 +14,27 @@ type MinerRepository struct {
 	*sql.DB
 }
 
+// A miner's own payment threshold for a chain, payouts never go below the pool minimum
 type MinerSettings struct {
 	PoolID           string
 	Miner            string
+	Chain            string
 	PaymentThreshold float32
 	Created          time.Time
 	Updated          time.Time
 }
 
-func (r *MinerRepository) GetSettings(poolID, miner string) (MinerSettings, error) {
+func (r *MinerRepository) GetSettings(poolID, miner, chain string) (MinerSettings, error) {
 	var settings MinerSettings
-	query := "SELECT poolid, address, paymentthreshold, created, updated FROM miner_settings WHERE poolid = $1 AND address = $2"
+	query := "SELECT poolid, address, chain, paymentthreshold, created, updated FROM miner_settings "
+	query = query + "WHERE poolid = $1 AND address = $2 AND chain = $3"
 
 	stmt, err := r.DB.Prepare(query)
 	if err != nil {
 		return settings, err
 	}
 
-	err = stmt.QueryRow(poolID, miner).Scan(&settings.PoolID, &settings.Miner,
+	err = stmt.QueryRow(poolID, miner, chain).Scan(&settings.PoolID, &settings.Miner, &settings.Chain,
 		&settings.PaymentThreshold, &settings.Created, &settings.Updated)
 	if err != nil {
 		return settings, err
@@ -41,19 +44,17 @@ func (r *MinerRepository) GetSettings(poolID, miner string) (MinerSettings, erro
 }
 
 func (r *MinerRepository) UpdateSettings(settings MinerSettings) error {
-	query := "INSERT INTO miner_settings(poolid, address, paymentthreshold, created, updated) "
-	query = query + "VALUES($1, $2, $3, now(), now()) "
+	query := "INSERT INTO miner_settings(poolid, address, chain, paymentthreshold, created, updated) "
+	query = query + "VALUES($1, $2, $3, $4, now(), now()) "
 	query = query + "ON CONFLICT ON CONSTRAINT miner_settings_pkey DO UPDATE "
-	query = query + "SET paymentthreshold = $4, updated = now() "
-	query = query + "WHERE miner_settings.poolid = $5 AND miner_settings.address = $6"
+	query = query + "SET paymentthreshold = $4, updated = now()"
 
 	stmt, err := r.DB.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	_, err = stmt.Exec(settings.PoolID, settings.Miner, settings.PaymentThreshold,
-		settings.PaymentThreshold, settings.PoolID, settings.Miner)
+	_, err = stmt.Exec(settings.PoolID, settings.Miner, settings.Chain, settings.PaymentThreshold)
 	return err
 }
 
@@ -168,7 +169,8 @@ func (r *MinerRepository) GetMinerStatsReport(poolID, address string, payments *
 		accounts[chain] = account
 	}
 
-	totalPaid := `select chain, sum(amount) AS amount from payments where poolid = $1 and address = $2 group by chain`
+	totalPaid := `select chain, sum(amount) AS amount from payments where poolid = $1 and address = $2
+	and status NOT IN ('conflicted', 'dropped') group by chain`
 	rows, err = r.DB.Query(totalPaid, poolID, address)
 	if err != nil {
 		return nil, err
@@ -188,7 +190,8 @@ func (r *MinerRepository) GetMinerStatsReport(poolID, address string, payments *
 	}
 
 	totalPaidToday := `select chain, sum(amount) AS amount from payments
-	where poolid = $1 and address = $2 and created >= date_trunc('day', now())  group by chain`
+	where poolid = $1 and address = $2 and created >= date_trunc('day', now())
+	and status NOT IN ('conflicted', 'dropped') group by chain`
 	rows, err = r.DB.Query(totalPaidToday, poolID, address)
 	if err != nil {
 		return nil, err
@@ -7,6 +7,13 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	PaymentStatusBroadcast  = "broadcast"
+	PaymentStatusConfirmed  = "confirmed"
+	PaymentStatusConflicted = "conflicted" // Re-credited to the balance
+	PaymentStatusDropped    = "dropped"    // Re-credited to the balance
+)
+
 type Payment struct {
 	ID                          uint
 	PoolID                      string
@@ -14,6 +21,8 @@ type Payment struct {
 	Address                     string
 	Amount                      float64
 	TransactionConfirmationData string
+	Status                      string
+	Confirmations               int64
 	Created                     time.Time
 }
 
@@ -22,16 +31,20 @@ type PaymentRepository struct {
 }
 
 func (r *PaymentRepository) Insert(payment Payment) error {
-	query := "INSERT INTO payments(poolid, chain, address, amount, transactionconfirmationdata, created) "
-	query = query + "VALUES($1, $2, $3, $4, $5, $6)"
+	query := "INSERT INTO payments(poolid, chain, address, amount, transactionconfirmationdata, status, created) "
+	query = query + "VALUES($1, $2, $3, $4, $5, $6, $7)"
 
 	stmt, err := r.DB.Prepare(query)
 	if err != nil {
 		return err
 	}
 
+	if payment.Status == "" {
+		payment.Status = PaymentStatusBroadcast
+	}
+
 	_, err = stmt.Exec(&payment.PoolID, &payment.Chain, &payment.Address, &payment.Amount,
-		&payment.TransactionConfirmationData, &payment.Created)
+		&payment.TransactionConfirmationData, &payment.Status, &payment.Created)
 	return err
 }
 
@@ -41,15 +54,18 @@ func (r *PaymentRepository) InsertBatch(payments []Payment) error {
 		return err
 	}
 
-	fields := pq.CopyIn("poolid", "chain", "address", "amount", "transactionconfirmationdata", "created")
+	fields := pq.CopyIn("poolid", "chain", "address", "amount", "transactionconfirmationdata", "status", "created")
 	stmt, err := txn.Prepare(fields)
 	if err != nil {
 		return err
 	}
 
 	for _, payment := range payments {
+		if payment.Status == "" {
+			payment.Status = PaymentStatusBroadcast
+		}
 		_, err = stmt.Exec(payment.PoolID, payment.Chain, payment.Address, payment.Amount,
-			payment.TransactionConfirmationData, payment.Created)
+			payment.TransactionConfirmationData, payment.Status, payment.Created)
 		if err != nil {
 			return err
 		}
@@ -69,7 +85,7 @@ func (r *PaymentRepository) InsertBatch(payments []Payment) error {
 }
 
 func (r *PaymentRepository) PagePayments(poolID, miner string, page, pageSize int) ([]Payment, error) {
-	query := "SELECT poolid, chain, address, amount, transactionconfirmationdata, created FROM payments WHERE poolid = $1 "
+	query := "SELECT poolid, chain, address, amount, transactionconfirmationdata, status, confirmations, created FROM payments WHERE poolid = $1 "
 	if miner != "" {
 		query = query + " AND address = $4 "
 	}
@@ -95,7 +111,7 @@ func (r *PaymentRepository) PagePayments(poolID, miner string, page, pageSize in
 		var payment Payment
 
 		err = rows.Scan(&payment.PoolID, &payment.Chain, &payment.Address, &payment.Amount,
-			&payment.TransactionConfirmationData, &payment.Created)
+			&payment.TransactionConfirmationData, &payment.Status, &payment.Confirmations, &payment.Created)
 		if err != nil {
 			return payments, err
 		}
@@ -108,6 +124,7 @@ func (r *PaymentRepository) PagePayments(poolID, miner string, page, pageSize in
 
 func (r *PaymentRepository) PageMinerPaymentsByDay(poolID, miner string, page, pageSize int) ([]Payment, error) {
 	query := "SELECT SUM(amount) AS amount, date_trunc('day', created) AS date FROM payments WHERE poolid = $1 "
+	query = query + "AND status NOT IN ('conflicted', 'dropped') "
 	if miner != "" {
 		query = query + " AND address = $4 "
 	}
@@ -205,7 +222,7 @@ func (r *PaymentRepository) MinerPaymentsByDayCount(poolID, miner string) (Payme
 }
 
 func (r *PaymentRepository) MinerLastPayments(poolID, miner string) (map[string]Payment, error) {
-	query := `SELECT poolid, chain, address, amount, transactionconfirmationdata, created
+	query := `SELECT poolid, chain, address, amount, transactionconfirmationdata, status, confirmations, created
 
 			FROM payments
 
@@ -229,7 +246,7 @@ func (r *PaymentRepository) MinerLastPayments(poolID, miner string) (map[string]
 	for rows.Next() {
 		var payment Payment
 		err = rows.Scan(&payment.PoolID, &payment.Chain, &payment.Address,
-			&payment.Amount, &payment.TransactionConfirmationData, &payment.Created)
+			&payment.Amount, &payment.TransactionConfirmationData, &payment.Status, &payment.Confirmations, &payment.Created)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,346 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	BatchStatusReserved  = "reserved"  // Sent or about to be sent by the wallet, see payouts/ledger.go
+	BatchStatusUnsigned  = "unsigned"  // Waiting for offline signing, see payouts/offline.go
+	BatchStatusBroadcast = "broadcast" // Tracked until confirmed, see payouts/tracking.go
+	BatchStatusConfirmed = "confirmed"
+	BatchStatusCancelled = "cancelled"
+	// Conflicted and dropped batches had their balances credited back, to be paid again
+	BatchStatusConflicted = "conflicted"
+	BatchStatusDropped    = "dropped"
+)
+
+const (
+	BatchFormatWallet = "wallet" // Built and signed by the wallet with sendmany
+)
+
+// A payout intent.  Its balances are reserved on insert, its payments recorded on
+// broadcast, and both happen atomically so a crash can never pay a balance twice.
+type PayoutBatch struct {
+	ID                  uint
+	PoolID              string
+	Chain               string
+	Status              string
+	Format              string // wallet, psbt or raw
+	UnsignedTransaction string
+	TransactionID       string
+	WalletNode          string // URL of the node whose wallet sends a wallet format batch, or funded an offline one
+	Items               []PayoutBatchItem
+	Created             time.Time
+	Updated             time.Time
+}
+
+type PayoutBatchItem struct {
+	BalanceAddress string // The balances row to deduct from
+	Address        string // The chain address being paid
+	Amount         float64
+}
+
+type PayoutBatchRepository struct {
+	*sql.DB
+}
+
+// Records the batch and deducts its amounts from the balances in one transaction
+func (r *PayoutBatchRepository) Insert(batch PayoutBatch) (uint, error) {
+	txn, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	query := `INSERT INTO payout_batches(poolid, chain, status, format, unsignedtransaction, walletnode, created, updated)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	var id uint
+	err = txn.QueryRow(query, batch.PoolID, batch.Chain, batch.Status, batch.Format,
+		batch.UnsignedTransaction, batch.WalletNode, batch.Created, batch.Created).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	query = "INSERT INTO payout_batch_items(batchid, balanceaddress, address, amount) VALUES($1, $2, $3, $4)"
+	for _, item := range batch.Items {
+		_, err = txn.Exec(query, id, item.BalanceAddress, item.Address, item.Amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	usage := fmt.Sprintf("Reserved for payout batch %v", id)
+	for _, item := range batch.Items {
+		err = changeBalance(txn, batch.PoolID, batch.Chain, item.BalanceAddress, usage, -item.Amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, txn.Commit()
+}
+
+// Marks a reserved or unsigned batch broadcast and records its payments, only once
+func (r *PayoutBatchRepository) MarkBroadcast(batch PayoutBatch, transactionID string) error {
+	txn, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	err = changeStatus(txn, batch.ID, BatchStatusBroadcast, transactionID, BatchStatusReserved, BatchStatusUnsigned)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO payments(poolid, chain, address, amount, transactionconfirmationdata, batchid, status, created)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
+	now := time.Now()
+	for _, item := range batch.Items {
+		_, err = txn.Exec(query, batch.PoolID, batch.Chain, item.Address, item.Amount, transactionID,
+			batch.ID, PaymentStatusBroadcast, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
+
+// For batches that were never sent, their reserved balances are credited back
+func (r *PayoutBatchRepository) Cancel(batch PayoutBatch) error {
+	txn, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	err = changeStatus(txn, batch.ID, BatchStatusCancelled, "", BatchStatusReserved, BatchStatusUnsigned)
+	if err != nil {
+		return err
+	}
+
+	usage := fmt.Sprintf("Released from cancelled payout batch %v", batch.ID)
+	for _, item := range batch.Items {
+		err = changeBalance(txn, batch.PoolID, batch.Chain, item.BalanceAddress, usage, item.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
+
+// Records the confirmations of a broadcast batch's payments
+func (r *PayoutBatchRepository) UpdateConfirmations(batch PayoutBatch, confirmations int64) error {
+	query := "UPDATE payments SET confirmations = $1 WHERE batchid = $2 AND status = $3"
+	_, err := r.DB.Exec(query, confirmations, batch.ID, PaymentStatusBroadcast)
+	return err
+}
+
+func (r *PayoutBatchRepository) MarkConfirmed(batch PayoutBatch, confirmations int64) error {
+	txn, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	err = changeStatus(txn, batch.ID, BatchStatusConfirmed, "", BatchStatusBroadcast)
+	if err != nil {
+		return err
+	}
+
+	err = changePaymentsStatus(txn, batch.ID, PaymentStatusConfirmed, confirmations)
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
+// Follows a fee bump, the replacement transaction pays the same batch
+func (r *PayoutBatchRepository) Replace(batch PayoutBatch, transactionID string) error {
+	txn, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	err = changeStatus(txn, batch.ID, BatchStatusBroadcast, transactionID, BatchStatusBroadcast)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE payments SET transactionconfirmationdata = $1, confirmations = 0 WHERE batchid = $2 AND status = $3"
+	_, err = txn.Exec(query, transactionID, batch.ID, PaymentStatusBroadcast)
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
+// For broadcast batches that will never confirm, either conflicted or dropped,
+// their payments are voided and balances credited back to be paid again
+func (r *PayoutBatchRepository) Recredit(batch PayoutBatch, status string, confirmations int64) error {
+	if status != BatchStatusConflicted && status != BatchStatusDropped {
+		return fmt.Errorf("can't re-credit payout batch %v as %v", batch.ID, status)
+	}
+
+	txn, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	err = changeStatus(txn, batch.ID, status, "", BatchStatusBroadcast)
+	if err != nil {
+		return err
+	}
+
+	// The payment and batch statuses share names
+	err = changePaymentsStatus(txn, batch.ID, status, confirmations)
+	if err != nil {
+		return err
+	}
+
+	usage := fmt.Sprintf("Re-credited from %v payout batch %v", status, batch.ID)
+	for _, item := range batch.Items {
+		err = changeBalance(txn, batch.PoolID, batch.Chain, item.BalanceAddress, usage, item.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
+
+// Moves a batch to status from one of the given statuses, keeping its transaction ID when none is given
+func changeStatus(txn *sql.Tx, id uint, status, transactionID string, from ...string) error {
+	query := `UPDATE payout_batches SET status = $1, transactionid = COALESCE(NULLIF($2, ''), transactionid), updated = $3
+	WHERE id = $4 AND status = ANY($5)`
+
+	result, err := txn.Exec(query, status, transactionID, time.Now(), id, pq.Array(from))
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return errors.New(fmt.Sprintf("payout batch %v is no longer %v", id, strings.Join(from, " or ")))
+	}
+	return nil
+}
+
+func changePaymentsStatus(txn *sql.Tx, batchID uint, status string, confirmations int64) error {
+	query := "UPDATE payments SET status = $1, confirmations = $2 WHERE batchid = $3 AND status = $4"
+	_, err := txn.Exec(query, status, confirmations, batchID, PaymentStatusBroadcast)
+	return err
+}
+
+// A reservation can't take a balance below zero
+func changeBalance(txn *sql.Tx, poolID, chain, address, usage string, amount float64) error {
+	now := time.Now()
+	query := `INSERT INTO balance_changes(poolid, chain, address, amount, usage, created)
+	VALUES($1, $2, $3, $4, $5, $6)`
+	_, err := txn.Exec(query, poolID, chain, address, amount, usage, now)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE balances SET amount = amount + $1, updated = $2
+	WHERE poolid = $3 AND chain = $4 AND address = $5 AND amount + $1 >= 0`
+	result, err := txn.Exec(query, amount, now, poolID, chain, address)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return fmt.Errorf("%v balance of %v can't cover %v", chain, address, -amount)
+	}
+	return nil
+}
+
+func (r *PayoutBatchRepository) HasUnsigned(poolID, chain string) (bool, error) {
+	query := "SELECT COUNT(*) FROM payout_batches WHERE poolid = $1 AND chain = $2 AND status = $3"
+
+	var count int
+	err := r.DB.QueryRow(query, poolID, chain, BatchStatusUnsigned).Scan(&count)
+	return count > 0, err
+}
+
+func (r *PayoutBatchRepository) GetByStatus(poolID, status string) ([]PayoutBatch, error) {
+	query := `SELECT id FROM payout_batches WHERE poolid = $1 AND status = $2 ORDER BY id`
+
+	rows, err := r.DB.Query(query, poolID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	batches := make([]PayoutBatch, len(ids))
+	for i, id := range ids {
+		batch, err := r.Get(poolID, id)
+		if err != nil {
+			return nil, err
+		}
+		batches[i] = *batch
+	}
+	return batches, nil
+}
+
+func (r *PayoutBatchRepository) Get(poolID string, id uint) (*PayoutBatch, error) {
+	query := `SELECT id, poolid, chain, status, format, unsignedtransaction, COALESCE(transactionid, ''), walletnode, created, updated
+	FROM payout_batches WHERE poolid = $1 AND id = $2`
+
+	var batch PayoutBatch
+	err := r.DB.QueryRow(query, poolID, id).Scan(&batch.ID, &batch.PoolID, &batch.Chain, &batch.Status,
+		&batch.Format, &batch.UnsignedTransaction, &batch.TransactionID, &batch.WalletNode, &batch.Created, &batch.Updated)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("payout batch %v not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = "SELECT balanceaddress, address, amount FROM payout_batch_items WHERE batchid = $1"
+	rows, err := r.DB.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item PayoutBatchItem
+		err = rows.Scan(&item.BalanceAddress, &item.Address, &item.Amount)
+		if err != nil {
+			return nil, err
+		}
+		batch.Items = append(batch.Items, item)
+	}
+
+	return &batch, nil
+}
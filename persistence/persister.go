@@ -14,6 +14,8 @@ var (
 	Payments PaymentRepository
 	Pool     PoolRepository
 	Shares   ShareRepository
+
+	PayoutBatches PayoutBatchRepository
 )
 
 func MakePersister(configuration *config.Config) error {
@@ -33,6 +35,7 @@ func MakePersister(configuration *config.Config) error {
 	Payments = PaymentRepository{db}
 	Pool = PoolRepository{db}
 	Shares = ShareRepository{db}
+	PayoutBatches = PayoutBatchRepository{db}
 
 	return nil
 }
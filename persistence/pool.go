@@ -61,7 +61,7 @@ func (r *PoolRepository) GetLastStat(poolID string) (PoolStat, error) {
 }
 
 func (r *PoolRepository) TotalPoolPayments(poolID string) (float32, error) {
-	query := "SELECT sum(amount) FROM payments WHERE poolid = $1"
+	query := "SELECT sum(amount) FROM payments WHERE poolid = $1 AND status NOT IN ('conflicted', 'dropped')"
 
 	stmt, err := r.DB.Prepare(query)
 	if err != nil {
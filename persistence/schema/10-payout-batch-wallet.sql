@@ -0,0 +1,5 @@
+SET ROLE mergedmining;
+
+/* The wallet node a batch was sent from, only it can tell whether a reserved batch went out.
+   Batches from before have none and are held for the operator. */
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS walletnode TEXT NOT NULL DEFAULT '';
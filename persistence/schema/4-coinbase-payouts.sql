@@ -0,0 +1,4 @@
+SET ROLE mergedmining;
+
+/* Blocks that paid their miners in the coinbase are never credited to balances */
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS paidincoinbase BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
@@ -0,0 +1,4 @@
+SET ROLE mergedmining;
+
+/* Blocks that paid their pool reward recipients in the coinbase never credit them */
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS poolrewardsincoinbase BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
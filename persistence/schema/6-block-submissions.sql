@@ -0,0 +1,4 @@
+SET ROLE mergedmining;
+
+/* Each node's response when the block was broadcast, as JSON */
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS submissions TEXT NULL;
@@ -0,0 +1,28 @@
+SET ROLE mergedmining;
+
+/* Unsigned payout transactions waiting for offline signing */
+CREATE TABLE payout_batches
+(
+	id BIGSERIAL NOT NULL PRIMARY KEY,
+	poolid TEXT NOT NULL,
+	chain TEXT NOT NULL,
+	status TEXT NOT NULL,
+	format TEXT NOT NULL,
+	unsignedtransaction TEXT NOT NULL,
+	transactionid TEXT NULL,
+	created TIMESTAMPTZ NOT NULL,
+	updated TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IDX_PAYOUT_BATCHES_POOL_CHAIN_STATUS on payout_batches(poolid, chain, status);
+
+/* The pending payments of a batch, balances are deducted when it's reserved and credited back if it's cancelled */
+CREATE TABLE payout_batch_items
+(
+	batchid BIGINT NOT NULL REFERENCES payout_batches(id),
+	balanceaddress TEXT NOT NULL,
+	address TEXT NOT NULL,
+	amount decimal(28,8) NOT NULL
+);
+
+CREATE INDEX IDX_PAYOUT_BATCH_ITEMS_BATCH on payout_batch_items(batchid);
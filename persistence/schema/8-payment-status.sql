@@ -0,0 +1,8 @@
+SET ROLE mergedmining;
+
+/* Payments are tracked until confirmed, payments made before tracking are assumed confirmed */
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS batchid BIGINT NULL;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'confirmed';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS confirmations BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS IDX_PAYMENTS_BATCH on payments(batchid);
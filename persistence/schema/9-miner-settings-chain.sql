@@ -0,0 +1,7 @@
+SET ROLE mergedmining;
+
+/* Payment thresholds are in each chain's coins, so they're set per chain.
+   Rows from before have no chain and no longer apply, the pool minimum does. */
+ALTER TABLE miner_settings ADD COLUMN IF NOT EXISTS chain TEXT NOT NULL DEFAULT '';
+ALTER TABLE miner_settings DROP CONSTRAINT IF EXISTS miner_settings_pkey;
+ALTER TABLE miner_settings ADD CONSTRAINT miner_settings_pkey PRIMARY KEY (poolid, address, chain);
@@ -8,6 +8,8 @@ DROP TABLE balance_changes;
 DROP TABLE miner_settings;
 DROP TABLE poolstats;
 DROP TABLE minerstats;
+DROP TABLE payout_batch_items;
+DROP TABLE payout_batches;
 
 CREATE TABLE shares
 (
@@ -39,6 +41,9 @@ CREATE TABLE blocks
 	reward decimal(28,8) NULL,
     source TEXT NULL,
     hash TEXT NULL,
+    paidincoinbase BOOLEAN NOT NULL DEFAULT FALSE,
+    poolrewardsincoinbase BOOLEAN NOT NULL DEFAULT FALSE,
+    submissions TEXT NULL,
 	created TIMESTAMPTZ NOT NULL,
 
     CONSTRAINT BLOCKS_POOL_HEIGHT UNIQUE (poolid, chain, blockheight) DEFERRABLE INITIALLY DEFERRED
@@ -72,11 +77,12 @@ CREATE TABLE miner_settings
 (
 	poolid TEXT NOT NULL,
 	address TEXT NOT NULL,
+	chain TEXT NOT NULL DEFAULT '',
 	paymentthreshold decimal(28,8) NOT NULL,
 	created TIMESTAMPTZ NOT NULL,
 	updated TIMESTAMPTZ NOT NULL,
 
-	primary key(poolid, address)
+	primary key(poolid, address, chain)
 );
 
 CREATE TABLE payments
@@ -87,9 +93,42 @@ CREATE TABLE payments
 	address TEXT NOT NULL,
 	amount decimal(28,8) NOT NULL,
 	transactionconfirmationdata TEXT NOT NULL,
+	batchid BIGINT NULL,
+	status TEXT NOT NULL DEFAULT 'confirmed',
+	confirmations BIGINT NOT NULL DEFAULT 0,
 	created TIMESTAMPTZ NOT NULL
 );
 
+CREATE INDEX IDX_PAYMENTS_BATCH on payments(batchid);
+
+/* Payout intents, from reserving their balances until their transaction confirms */
+CREATE TABLE payout_batches
+(
+	id BIGSERIAL NOT NULL PRIMARY KEY,
+	poolid TEXT NOT NULL,
+	chain TEXT NOT NULL,
+	status TEXT NOT NULL,
+	format TEXT NOT NULL,
+	unsignedtransaction TEXT NOT NULL,
+	transactionid TEXT NULL,
+	walletnode TEXT NOT NULL DEFAULT '',
+	created TIMESTAMPTZ NOT NULL,
+	updated TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IDX_PAYOUT_BATCHES_POOL_CHAIN_STATUS on payout_batches(poolid, chain, status);
+
+/* The pending payments of a batch, balances are deducted when it's reserved and credited back if it's cancelled */
+CREATE TABLE payout_batch_items
+(
+	batchid BIGINT NOT NULL REFERENCES payout_batches(id),
+	balanceaddress TEXT NOT NULL,
+	address TEXT NOT NULL,
+	amount decimal(28,8) NOT NULL
+);
+
+CREATE INDEX IDX_PAYOUT_BATCH_ITEMS_BATCH on payout_batch_items(batchid);
+
 CREATE TABLE poolstats
 (
 	id BIGSERIAL NOT NULL PRIMARY KEY,
@@ -0,0 +1,184 @@
+package pool
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/payouts"
+)
+
+const (
+	satoshisPerCoin            = 100000000
+	defaultCoinbaseMaxOutputs  = 50
+	coinbaseReservedPoolOutput = 1
+)
+
+var (
+	addressScripts     = make(map[string][]byte) // "chain:address" => pubScriptKey
+	addressScriptsLock sync.Mutex
+)
+
+type coinbasePayout struct {
+	address string
+	amount  float64 // Satoshis
+}
+
+// Fee recipients and, in coinbase payouts mode, the PPLNS window split the coinbase;
+// the pool's output takes the rest
+func (p *PoolServer) makeCoinbasePayouts(coinbaseValue uint) ([]bitcoin.CoinbaseOutput, error) {
+	chain := p.config.GetPrimary()
+	payoutConfig, exists := p.config.Payouts.Chains[chain]
+	if !exists || !(payoutConfig.CoinbasePayouts || payoutConfig.PoolRewardsInCoinbase) {
+		return nil, nil
+	}
+
+	rewardTo := p.GetPrimaryNode().RewardTo
+
+	maxOutputs := payoutConfig.CoinbaseMaxOutputs
+	if maxOutputs < 1 {
+		maxOutputs = defaultCoinbaseMaxOutputs
+	}
+
+	value := float64(coinbaseValue)
+	minersValue := value
+	var outputs []bitcoin.CoinbaseOutput
+	for _, poolRecipient := range payoutConfig.PoolRewardRecipients {
+		fee := poolRecipient.Percentage * value
+		minersValue -= fee
+		if poolRecipient.Address == rewardTo { // Stays in the pool's output
+			continue
+		}
+		script, err := p.addressPubScriptKey(chain, poolRecipient.Address)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, bitcoin.CoinbaseOutput{
+			Amount:       uint64(fee),
+			PubScriptKey: script,
+			PoolReward:   true,
+		})
+	}
+
+	if !payoutConfig.CoinbasePayouts {
+		return outputs, nil
+	}
+
+	fractions, err := payouts.PPLNSWindowFractions(p.config.PoolName, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	minerAmounts := make(map[string]float64)
+	for miner, fraction := range fractions {
+		// Shares are keyed by primaryAddress-auxAddress..
+		address := strings.Split(miner, "-")[0]
+		minerAmounts[address] += fraction * minersValue
+	}
+
+	minerOutputs := maxOutputs - len(outputs) - coinbaseReservedPoolOutput
+	dust := payoutConfig.CoinbaseDustThreshold * satoshisPerCoin
+	miners, err := p.trimCoinbasePayouts(minerAmounts, dust, minerOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	fees := len(outputs)
+	for _, payout := range miners {
+		script, err := p.addressPubScriptKey(chain, payout.address)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, bitcoin.CoinbaseOutput{
+			Amount:       uint64(payout.amount),
+			PubScriptKey: script,
+		})
+	}
+
+	log.Printf("Coinbase pays %v miner(s) and %v fee recipient(s) of %v window participant(s)", len(miners), fees, len(minerAmounts))
+
+	return outputs, nil
+}
+
+// Drops dust, invalid addresses and the smallest payouts past maxOutputs,
+// then spreads what they would have had over the miners that are left
+func (p *PoolServer) trimCoinbasePayouts(minerAmounts map[string]float64, dust float64, maxOutputs int) ([]coinbasePayout, error) {
+	if maxOutputs < 1 {
+		return nil, errors.New("coinbase max outputs leaves no room for miners")
+	}
+
+	var candidates []coinbasePayout
+	total := float64(0)
+	for address, amount := range minerAmounts {
+		candidates = append(candidates, coinbasePayout{address, amount})
+		total += amount
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].amount == candidates[j].amount {
+			return candidates[i].address < candidates[j].address
+		}
+		return candidates[i].amount > candidates[j].amount
+	})
+
+	var kept []coinbasePayout
+	keptTotal := float64(0)
+	for _, candidate := range candidates {
+		if len(kept) == maxOutputs || candidate.amount < dust {
+			break
+		}
+		_, err := p.addressPubScriptKey(p.config.GetPrimary(), candidate.address)
+		if err != nil {
+			log.Printf("Leaving %v out of the coinbase: %v", candidate.address, err)
+			continue
+		}
+		kept = append(kept, candidate)
+		keptTotal += candidate.amount
+	}
+
+	for i := range kept {
+		kept[i].amount = kept[i].amount * total / keptTotal
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].address < kept[j].address
+	})
+
+	return kept, nil
+}
+
+func (p *PoolServer) addressPubScriptKey(chain, address string) ([]byte, error) {
+	key := chain + ":" + address
+
+	addressScriptsLock.Lock()
+	script, exists := addressScripts[key]
+	addressScriptsLock.Unlock()
+	if exists {
+		return script, nil
+	}
+
+	response, err := p.rpcManagers[chain].GetActiveClient().ValidateAddress(context.Background(), address)
+	if err != nil {
+		return nil, err
+	}
+	if response.ScriptPubKey == "" {
+		return nil, fmt.Errorf("invalid %v address: %v", chain, address)
+	}
+
+	script, err = hex.DecodeString(response.ScriptPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	addressScriptsLock.Lock()
+	addressScripts[key] = script
+	addressScriptsLock.Unlock()
+
+	return script, nil
+}
@@ -0,0 +1,119 @@
+package pool
+
+import (
+	"errors"
+	"log"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/config"
+)
+
+// Scrypt needs 128KB per hash, so concurrent share hashing is bounded
+// by a fixed set of workers instead of one hash per connection goroutine.
+
+var (
+	errHasherBusy    = errors.New("share hasher queue full")
+	errHasherTimeout = errors.New("share hashing timed out")
+)
+
+type hashJob struct {
+	block    *bitcoin.BitcoinBlock
+	deadline time.Time
+	result   chan error
+}
+
+type shareHasher struct {
+	jobs    chan hashJob
+	workers int
+	timeout time.Duration
+
+	hashed   atomic.Uint64
+	shed     atomic.Uint64
+	timedOut atomic.Uint64
+	expired  atomic.Uint64
+}
+
+func makeShareHasher(cfg config.HasherConfig) *shareHasher {
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	queueSize := cfg.QueueSize
+	if queueSize < 1 {
+		queueSize = workers * 4
+	}
+
+	timeout := 2 * time.Second
+	if cfg.Timeout != "" {
+		timeout = mustParseDuration(cfg.Timeout)
+	}
+
+	return &shareHasher{
+		jobs:    make(chan hashJob, queueSize),
+		workers: workers,
+		timeout: timeout,
+	}
+}
+
+func (h *shareHasher) start(metricsInterval time.Duration) {
+	for i := 0; i < h.workers; i++ {
+		go h.work()
+	}
+	go h.logMetricsAtInterval(metricsInterval)
+	log.Printf("Share hasher running %v workers with a queue of %v\n", h.workers, cap(h.jobs))
+}
+
+func (h *shareHasher) work() {
+	for job := range h.jobs {
+		// Nobody is waiting on it anymore
+		if time.Now().After(job.deadline) {
+			h.expired.Add(1)
+			job.result <- errHasherTimeout
+			continue
+		}
+
+		_, err := job.block.Sum()
+		h.hashed.Add(1)
+		job.result <- err
+	}
+}
+
+// Blocks until the block's proof of work hash is cached, see BitcoinBlock.Sum.
+// The block must not be touched by the caller if an error is returned.
+func (h *shareHasher) hash(block *bitcoin.BitcoinBlock) error {
+	job := hashJob{
+		block:    block,
+		deadline: time.Now().Add(h.timeout),
+		result:   make(chan error, 1),
+	}
+
+	select {
+	case h.jobs <- job:
+	default:
+		h.shed.Add(1)
+		return errHasherBusy
+	}
+
+	timer := time.NewTimer(h.timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-job.result:
+		return err
+	case <-timer.C:
+		h.timedOut.Add(1)
+		return errHasherTimeout
+	}
+}
+
+func (h *shareHasher) logMetricsAtInterval(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		m := "Share hasher - queued: %v/%v, hashed: %v, shed: %v, timed out: %v, expired in queue: %v"
+		log.Printf(m, len(h.jobs), cap(h.jobs), h.hashed.Load(), h.shed.Load(), h.timedOut.Load(), h.expired.Load())
+	}
+}
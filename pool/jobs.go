@@ -0,0 +1,55 @@
+package pool
+
+import (
+	"sync"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+// Enough to cover a few template refreshes on the same previous block
+const maxRegisteredJobs = 16
+
+type job struct {
+	block    bitcoin.BitcoinBlock
+	auxBlock *bitcoin.AuxBlock
+}
+
+// Shares are validated against the job they were mined on, not whatever work is current
+type jobRegistry struct {
+	sync.RWMutex
+	jobs  map[string]job
+	order []string
+}
+
+func makeJobRegistry() *jobRegistry {
+	return &jobRegistry{
+		jobs: make(map[string]job),
+	}
+}
+
+// A job on a new previous block makes every older job stale
+func (r *jobRegistry) add(jobID string, j job, clean bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if clean {
+		r.jobs = make(map[string]job)
+		r.order = nil
+	}
+
+	r.jobs[jobID] = j
+	r.order = append(r.order, jobID)
+
+	for len(r.order) > maxRegisteredJobs {
+		delete(r.jobs, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+func (r *jobRegistry) get(jobID string) (job, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	j, exists := r.jobs[jobID]
+	return j, exists
+}
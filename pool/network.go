@@ -20,6 +20,8 @@ type stratumClient struct {
 	extranonce1 string
 	userAgent   string
 
+	versionRollingMask uint32 // Negotiated through mining.configure
+
 	sessionID     string
 	connection    net.Conn
 	streamEncoder *json.Encoder
@@ -3,9 +3,13 @@ package pool
 import (
     "context"
     "encoding/hex"
+    "encoding/json"
+    "errors"
     "fmt"
     "log"
     "strings"
+    "sync"
+    "time"
 
     "designs.capital/dogepool/bitcoin"
     "designs.capital/dogepool/rpc"
@@ -16,7 +20,6 @@ type BlockChainNodesMap map[string]blockChainNode // "blockChainName" => activeN
 
 type blockChainNode struct {
     NotifyURL          string
-    RPC                *rpc.RPCClient
     ChainName          string
     Network            string
     RewardPubScriptKey string // TODO - this is very bitcoin specific.  Abstract to interface.
@@ -44,10 +47,10 @@ func (pool *PoolServer) loadBlockchainNodes() {
         rpcClient := rpcManager.GetActiveClient()
         nodeConfig := pool.config.BlockchainNodes[blockChainName][rpcManager.GetIndex()]
 
-        chainInfo, err := rpcClient.GetBlockChainInfo()
+        chainInfo, err := rpcClient.GetBlockChainInfo(context.Background())
         logFatalOnError(err)
 
-        address, err := rpcClient.ValidateAddress(nodeConfig.RewardTo)
+        address, err := rpcClient.ValidateAddress(context.Background(), nodeConfig.RewardTo)
         logFatalOnError(err)
 
         // TODO this is wayy to bitcoin specific.  Move this to the coin package.
@@ -55,7 +58,6 @@ func (pool *PoolServer) loadBlockchainNodes() {
 
         newNode := blockChainNode{
             NotifyURL:          nodeConfig.NotifyURL,
-            RPC:                rpcClient,
             Network:            chainInfo.Chain,
             RewardPubScriptKey: rewardPubScriptKey,
             RewardTo:           nodeConfig.RewardTo,
@@ -95,6 +97,17 @@ func (pool *PoolServer) listenForBlockNotifications() error {
 
         hashblockCounterMap[chainName] = newCount
 
+        if chainName == pool.config.GetPrimary() {
+            err := pool.cacheEmptyWork(prevBlockHash)
+            if err != nil {
+                log.Printf("Skipping empty job: %v", err)
+            } else {
+                work, err := pool.generateWorkFromCache(true)
+                logOnError(err)
+                pool.broadcastWork(work)
+            }
+        }
+
         err := pool.fetchRpcBlockTemplatesAndCacheWork()
         logOnError(err)
         work, err := pool.generateWorkFromCache(true)
@@ -104,21 +117,110 @@ func (pool *PoolServer) listenForBlockNotifications() error {
 }
 
 // Ultimate program OUTPUT
-func (p *PoolServer) submitBlockToChain(block *bitcoin.BitcoinBlock) error {
-    primaryNode := p.GetPrimaryNode()
-    blockHex := block.ToHex()
-    
-    response, err := primaryNode.RPC.SubmitBlock([]interface{}{blockHex})
+func (p *PoolServer) submitBlockToChain(block *bitcoin.BitcoinBlock) (submissionResults, error) {
+    blockHex, err := block.Submit()
+    if err != nil {
+        return nil, fmt.Errorf("error serializing block: %v", err)
+    }
+
+    results := p.submitToAllNodes(p.config.GetPrimary(), func(ctx context.Context, client *rpc.RPCClient) (bool, error) {
+        return client.SubmitBlock(ctx, []interface{}{blockHex})
+    })
+    if !results.accepted() {
+        return results, fmt.Errorf("no %v node accepted block %v: %v", block.ChainName(), block.Template.Height, results)
+    }
+
+    log.Printf("Successfully submitted %v block to chain: %v %v", block.ChainName(), block.Template.Height, results)
+    return results, nil
+}
+
+const (
+    submissionAccepted = "accepted"
+    submissionTimedOut = "timed out"
+    submitTimeout      = 10 * time.Second
+)
+
+type submissionResults map[string]string // "nodeName" => "accepted", "timed out" or the node's error
+
+func (r submissionResults) accepted() bool {
+    for _, result := range r {
+        if result == submissionAccepted {
+            return true
+        }
+    }
+    return false
+}
+
+func (r submissionResults) String() string {
+    encoded, err := json.Marshal(map[string]string(r))
     if err != nil {
-        if strings.Contains(err.Error(), "high-hash") {
-            log.Printf("Block rejected due to high hash value: %s", err)
-            return nil // Don't treat high-hash as an error, it's expected for some shares
+        return ""
+    }
+    return string(encoded)
+}
+
+// Propagation speed is revenue, so every healthy node of the chain gets the block at once.
+// A node that fails or misses the deadline just records its error.
+func (p *PoolServer) submitToAllNodes(chainName string, submit func(context.Context, *rpc.RPCClient) (bool, error)) submissionResults {
+    manager := p.rpcManagers[chainName]
+    clients := manager.GetHealthyClients()
+
+    // A hung node mustn't hold the share handler
+    ctx, cancel := context.WithTimeout(context.Background(), submitTimeout)
+    defer cancel()
+
+    results := make(submissionResults, len(clients))
+    var lock sync.Mutex
+    var wait sync.WaitGroup
+    for i, client := range clients {
+        nodeName := client.Name
+        if nodeName == "" {
+            nodeName = fmt.Sprintf("node-%v", i)
         }
-        return fmt.Errorf("error submitting block: %v", err)
+
+        wait.Add(1)
+        go func(nodeName string, client *rpc.RPCClient) {
+            defer wait.Done()
+            result := submissionAccepted
+            _, err := submit(ctx, client)
+            switch {
+            case errors.Is(err, context.DeadlineExceeded):
+                result = submissionTimedOut
+            // Another of our nodes may have relayed it first
+            case err != nil && !strings.Contains(err.Error(), "duplicate"):
+                result = err.Error()
+            }
+            lock.Lock()
+            results[nodeName] = result
+            lock.Unlock()
+        }(nodeName, client)
+    }
+    wait.Wait()
+
+    return results
+}
+
+// Failovers move the pool and payouts together since they share the managers,
+// the pool just needs fresh work from the new node
+func (pool *PoolServer) listenForFailovers() {
+    for chainName, manager := range pool.rpcManagers {
+        go func(chainName string, events <-chan rpc.Event) {
+            for event := range events {
+                log.Printf("⚠️  %v node %v: %v -> %v", chainName, event.Kind, event.From, event.To)
+                if chainName != pool.config.GetPrimary() {
+                    continue
+                }
+                err := pool.fetchRpcBlockTemplatesAndCacheWork()
+                if err != nil {
+                    log.Println(err)
+                    continue
+                }
+                work, err := pool.generateWorkFromCache(true)
+                logOnError(err)
+                pool.broadcastWork(work)
+            }
+        }(chainName, manager.Subscribe())
     }
-    
-    log.Printf("Successfully submitted block to chain: %s", response)
-    return nil
 }
 
 type hashBlockResponse struct {
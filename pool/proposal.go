@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/rpc"
+)
+
+const proposalNonce = "00000000"
+
+// Builds a block from the job with placeholder nonces and asks a node other than
+// the template's source whether it would accept it, proof of work aside.  Template
+// and coinbase bugs show up here instead of on a real block.
+func (p *PoolServer) proposeJob(block bitcoin.BitcoinBlock, source *rpc.RPCClient) error {
+	if block.Template == nil {
+		return nil
+	}
+
+	// The source node would only check its own template
+	node := p.rpcManagers[p.config.GetPrimary()].GetHealthyClientExcept(source)
+	if node == nil {
+		log.Printf("No other healthy %v node to propose block %v to", block.ChainName(), block.Template.Height)
+		return nil
+	}
+
+	extranonce := strings.Repeat("00", extranonce1Length+p.extranonce2Size)
+	nonceTime := fmt.Sprintf("%08x", block.Template.CurrentTime)
+	err := block.MakeHeader(extranonce, proposalNonce, nonceTime)
+	if err != nil {
+		return err
+	}
+
+	blockHex, err := block.Submit()
+	if err != nil {
+		return err
+	}
+
+	reason, err := node.ProposeBlock(context.Background(), blockHex)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case reason == "":
+		log.Printf("Proposal of %v block %v accepted by %v", block.ChainName(), block.Template.Height, node.Name)
+	case strings.HasPrefix(reason, "inconclusive"):
+		log.Printf("Proposal of %v block %v inconclusive on %v: %v", block.ChainName(), block.Template.Height, node.Name, reason)
+	default:
+		m := "⚠️  Proposal of %v block %v rejected by %v: %v"
+		return fmt.Errorf(m, block.ChainName(), block.Template.Height, node.Name, reason)
+	}
+
+	return nil
+}
+
+func (p *PoolServer) startTemplateProposals() {
+	if !p.config.TemplateProposals.Enabled {
+		return
+	}
+
+	interval := 10 * time.Minute
+	if p.config.TemplateProposals.Interval != "" {
+		interval = mustParseDuration(p.config.TemplateProposals.Interval)
+	}
+	log.Printf("Proposing the current template every %v\n", interval)
+
+	go func() {
+		for {
+			time.Sleep(interval)
+			// cacheWork replaces the templates under workLock
+			p.workLock.Lock()
+			block, source := p.templates.GetPrimary(), p.templateSource
+			p.workLock.Unlock()
+
+			err := p.proposeJob(block, source)
+			logOnError(err)
+		}
+	}()
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +25,12 @@ type stratumErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// https://en.bitcoin.it/wiki/Stratum_mining_protocol#mining.submit
+const (
+	stratumErrorOther       = 20
+	stratumErrorJobNotFound = 21
+)
+
 func (pool *PoolServer) respondToStratumClient(client *stratumClient, requestPayload []byte) error {
 	var request stratumRequest
 	err := json.Unmarshal(requestPayload, &request)
@@ -62,23 +69,25 @@ func handleStratumRequest(request *stratumRequest, client *stratumClient, pool *
         }
 
         // Check if version-rolling is requested
-        features, ok := params[0].([]interface{})
-        if ok {
-            for _, feature := range features {
-                if feature == "version-rolling" {
-                    // Return the same mask that miners request
-                    return stratumResponse{
-                        ID: request.Id,
-                        Result: map[string]interface{}{
-                            "version-rolling": true,
-                            "version-rolling.mask": "1fffe000",
-                            // Keep other features
-                            "minimum-difficulty": true,
-                            "subscribe-extranonce": true,
-                        },
-                        Error: nil,
-                    }, nil
-                }
+        var features []interface{}
+        if len(params) > 0 {
+            features, _ = params[0].([]interface{})
+        }
+        for _, feature := range features {
+            if feature == "version-rolling" {
+                mask := negotiateVersionRollingMask(params)
+                client.versionRollingMask = mask
+                return stratumResponse{
+                    ID: request.Id,
+                    Result: map[string]interface{}{
+                        "version-rolling": true,
+                        "version-rolling.mask": fmt.Sprintf("%08x", mask),
+                        // Keep other features
+                        "minimum-difficulty": true,
+                        "subscribe-extranonce": true,
+                    },
+                    Error: nil,
+                }, nil
             }
         }
 
@@ -94,7 +103,7 @@ func handleStratumRequest(request *stratumRequest, client *stratumClient, pool *
         }, nil
 
     case "mining.subscribe":
-        return miningSubscribe(request, client)
+        return miningSubscribe(request, client, pool)
     case "mining.authorize":
         return miningAuthorize(request, client, pool)
     case "mining.extranonce.subscribe":
@@ -109,7 +118,30 @@ func handleStratumRequest(request *stratumRequest, client *stratumClient, pool *
     }
 }
 
-func miningSubscribe(request *stratumRequest, client *stratumClient) (stratumResponse, error) {
+// BIP310: the miner may roll the bits both it and the pool allow
+func negotiateVersionRollingMask(params []interface{}) uint32 {
+	mask := uint32(poolVersionRollingMask)
+	if len(params) < 2 {
+		return mask
+	}
+
+	options, ok := params[1].(map[string]interface{})
+	if !ok {
+		return mask
+	}
+	requested, ok := options["version-rolling.mask"].(string)
+	if !ok {
+		return mask
+	}
+	requestedMask, err := strconv.ParseUint(requested, 16, 32)
+	if err != nil {
+		return mask
+	}
+
+	return mask & uint32(requestedMask)
+}
+
+func miningSubscribe(request *stratumRequest, client *stratumClient, pool *PoolServer) (stratumResponse, error) {
 	var response stratumResponse
 
 	if isBanned(client.ip) {
@@ -135,7 +167,7 @@ func miningSubscribe(request *stratumRequest, client *stratumClient) (stratumRes
 	difficulty := interface{}([]string{"mining.set_difficulty", client.sessionID})
 	notify := interface{}([]string{"mining.notify", client.sessionID})
 	extranonce1 := interface{}(client.extranonce1)
-	extranonce2Length := interface{}(4)
+	extranonce2Length := interface{}(pool.extranonce2Size)
 
 	subscriptions = append(subscriptions, difficulty)
 	subscriptions = append(subscriptions, notify)
@@ -255,6 +287,24 @@ func miningSubmit(request *stratumRequest, client *stratumClient, pool *PoolServ
 	}
 
 	err = pool.recieveWorkFromClient(work, client)
+	if errors.Is(err, errHasherBusy) || errors.Is(err, errHasherTimeout) {
+		// Shed load; the miner can resend the share
+		log.Printf("Share from %v not hashed: %v", client.ip, err)
+		response.Error = &stratumErrorResponse{
+			Code:    stratumErrorOther,
+			Message: "Server busy, retry share",
+		}
+		return response, nil
+	}
+	var rejection shareRejection
+	if errors.As(err, &rejection) {
+		log.Printf("Share from %v rejected: %v", client.ip, rejection.reason)
+		response.Error = &stratumErrorResponse{
+			Code:    rejection.code,
+			Message: rejection.reason,
+		}
+		return response, nil
+	}
 	if err != nil {
 		log.Println(err)
 	}
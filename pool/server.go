@@ -2,8 +2,10 @@ package pool
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net"
@@ -24,9 +26,16 @@ type PoolServer struct {
 	activeNodes       BlockChainNodesMap
 	rpcManagers       map[string]*rpc.Manager
 	connectionTimeout time.Duration
+	workLock          sync.Mutex // Block notifications and failovers both refresh work
 	templates         Pair
+	templateSource    *rpc.RPCClient // The node the primary template came from, guarded by workLock
 	workCache         bitcoin.Work
 	shareBuffer       []persistence.Share
+	jobs              *jobRegistry
+	hasher            *shareHasher
+	extranonce2Size   int
+	ntimeFutureWindow time.Duration
+	transactionPolicy bitcoin.TransactionPolicy
 }
 
 func NewServer(cfg *config.Config, rpcManagers map[string]*rpc.Manager) *PoolServer {
@@ -40,18 +49,60 @@ func NewServer(cfg *config.Config, rpcManagers map[string]*rpc.Manager) *PoolSer
 		log.Println("Pool must have a blockchain order to tell primary vs aux")
 	}
 
+	err := validateMergedChains(cfg.BlockChainOrder)
+	logFatalOnError(err)
+
+	policy := cfg.TransactionPolicy
+	transactionPolicy, err := bitcoin.MakeTransactionPolicy(policy.MaxWeight, policy.ExcludeTxIDs,
+		policy.ExcludePatterns, policy.MinFeeRate, policy.EmptyBlocks)
+	logFatalOnError(err)
+
 	pool := &PoolServer{
 		config:      cfg,
 		rpcManagers: rpcManagers,
+		hasher:      makeShareHasher(cfg.Hasher),
+		jobs:        makeJobRegistry(),
+
+		transactionPolicy: transactionPolicy,
+		extranonce2Size:   defaultExtranonce2Size,
+		ntimeFutureWindow: defaultNtimeFutureWindow,
+	}
+
+	if cfg.Extranonce2Size > 0 {
+		pool.extranonce2Size = cfg.Extranonce2Size
+	}
+	if cfg.NtimeFutureWindow != "" {
+		pool.ntimeFutureWindow = mustParseDuration(cfg.NtimeFutureWindow)
 	}
 
 	return pool
 }
 
+// AuxPow reuses the parent's header hash, so every chain must share its proof of work
+func validateMergedChains(order config.BlockChainOrder) error {
+	if len(order) < 2 {
+		return nil
+	}
+
+	primary := bitcoin.GetChain(order.GetPrimary())
+	for _, chainName := range order[1:] {
+		aux := bitcoin.GetChain(chainName)
+		if aux.Algorithm() != primary.Algorithm() {
+			m := "%v (%v) cannot be merge mined with %v (%v)"
+			return fmt.Errorf(m, chainName, aux.Algorithm(), primary.ChainName(), primary.Algorithm())
+		}
+	}
+
+	return nil
+}
+
 func (pool *PoolServer) Start() {
 	initiateSessions()
 	pool.loadBlockchainNodes()
 	pool.startBufferManager()
+	pool.startShareHasher()
+	pool.startTemplateProposals()
+	pool.listenForFailovers()
 
 	// Add logging for initialization
 	log.Printf("Pool server starting with config: %+v", pool.config)
@@ -74,6 +125,14 @@ func (pool *PoolServer) Start() {
 	panicOnError(pool.listenForBlockNotifications())
 }
 
+func (pool *PoolServer) startShareHasher() {
+	interval := time.Minute
+	if pool.config.Hasher.MetricsInterval != "" {
+		interval = mustParseDuration(pool.config.Hasher.MetricsInterval)
+	}
+	pool.hasher.start(interval)
+}
+
 func (pool *PoolServer) handleConnection(conn net.Conn) {
 	// Add connection details logging
 	remoteAddr := conn.RemoteAddr().String()
@@ -137,29 +196,35 @@ func (pool *PoolServer) broadcastWork(work bitcoin.Work) {
 func (p *PoolServer) fetchAllBlockTemplatesFromRPC() (bitcoin.Template, *bitcoin.AuxBlock, error) {
 	var template bitcoin.Template
 	var err error
-	response, err := p.GetPrimaryNode().RPC.GetBlockTemplate()
+	// Lagging nodes produce stale templates, take the most up to date one
+	source := p.rpcManagers[p.config.GetPrimary()].GetBestClient(context.Background())
+	response, err := source.GetBlockTemplate(context.Background())
 	if err != nil {
 		return template, nil, errors.New("RPC error: " + err.Error())
 	}
+	p.templateSource = source
 
 	err = json.Unmarshal(response, &template)
 	if err != nil {
 		return template, nil, err
 	}
 
+	if p.config.GetAux1() == "" {
+		return template, nil, nil
+	}
+
 	var auxBlock bitcoin.AuxBlock
 
-	if p.config.GetAux1() != "" {
-		response, err = p.GetAux1Node().RPC.CreateAuxBlock(p.GetAux1Node().RewardTo)
-		if err != nil {
-			log.Println("No aux block found: " + err.Error())
-			return template, nil, nil
-		}
+	aux1Client := p.rpcManagers[p.config.GetAux1()].GetBestClient(context.Background())
+	response, err = aux1Client.CreateAuxBlock(context.Background(), p.GetAux1Node().RewardTo)
+	if err != nil {
+		log.Println("No aux block found: " + err.Error())
+		return template, nil, nil
+	}
 
-		err = json.Unmarshal(response, &auxBlock)
-		if err != nil {
-			return template, nil, err
-		}
+	err = json.Unmarshal(response, &auxBlock)
+	if err != nil {
+		return template, nil, err
 	}
 
 	return template, &auxBlock, nil
@@ -52,61 +52,67 @@ func InitVarDiff(config *config.Config) {
     }
 }
 
-// Update function signature to remove poolDifficulty parameter
+// The share's own hash decides whether it meets the miner's difficulty and whether it
+// solves either chain.  It's credited at the assigned difficulty, a lucky hash counts no more.
 func validateAndWeighShare(primaryBlockTemplate *bitcoin.BitcoinBlock, auxBlock *bitcoin.AuxBlock, minerAddr string) (int, float64) {
-    // Get header hash using HeaderHashed method
-    headerHash, err := primaryBlockTemplate.HeaderHashed()
+    hash, err := primaryBlockTemplate.Sum()
     if err != nil {
         log.Printf("Error calculating header digest: %v", err)
         return shareInvalid, 0
     }
-    log.Printf("Header hash: %s", headerHash)
-    
-    // Calculate difficulty from target bits
-    target := bitcoin.Target(primaryBlockTemplate.Template.Bits)
-    shareDifficulty, _ := target.ToDifficulty()  // Remove unused accuracy variable
-    if shareDifficulty == 0 {
-        log.Printf("Error: Invalid share difficulty calculated from bits %s", primaryBlockTemplate.Template.Bits)
+    if hash.Sign() == 0 {
+        log.Printf("Error: share hashed to zero")
         return shareInvalid, 0
     }
-    
+
+    hashTarget := bitcoin.Target(hash.Text(16))
+    shareDifficulty, _ := hashTarget.ToDifficulty()
+    shareDifficulty = shareDifficulty * primaryBlockTemplate.ShareMultiplier()
+
     // Get updated difficulty for this miner
     currentDiff := getUpdatedDifficulty(minerAddr, shareDifficulty)
     if currentDiff == 0 {
         currentDiff = minDifficulty // Ensure we never have 0 difficulty
     }
-    
+
     log.Printf("Share difficulty: %f, Current miner difficulty: %f", shareDifficulty, currentDiff)
-    
+
     if shareDifficulty < currentDiff {
-        log.Printf("Share rejected - Difficulty too low (share: %f < required: %f)", 
+        log.Printf("Share rejected - Difficulty too low (share: %f < required: %f)",
                   shareDifficulty, currentDiff)
         return shareInvalid, shareDifficulty
     }
 
-    // Get network difficulty from target bits
-    networkTarget := bitcoin.Target(primaryBlockTemplate.Template.Bits)
-    networkDiff, _ := networkTarget.ToDifficulty()
-    
-    // Check if this is a block candidate
-    if shareDifficulty >= networkDiff {
-        if auxBlock != nil {
-            // Check aux chain target
-            auxTarget := bitcoin.Target(auxBlock.Target)
-            auxDiff, _ := auxTarget.ToDifficulty()  // Remove unused auxAccuracy variable
-            if auxDiff == 0 {
-                log.Printf("Warning: Invalid aux difficulty calculated from target %s", auxBlock.Target)
-                return primaryCandidate, shareDifficulty
-            }
-            if shareDifficulty >= auxDiff {
-                return dualCandidate, shareDifficulty
-            }
-            return primaryCandidate, shareDifficulty
+    primaryTarget := bitcoin.Target(primaryBlockTemplate.Template.Target)
+    primaryTargetBig, ok := primaryTarget.ToBig()
+    if !ok {
+        log.Printf("Warning: Invalid primary target %s", primaryBlockTemplate.Template.Target)
+        return shareValid, currentDiff
+    }
+    primary := hash.Cmp(primaryTargetBig) <= 0
+
+    aux := false
+    if auxBlock != nil && auxBlock.Target != "" {
+        // createauxblock returns the target little endian
+        auxTarget := bitcoin.Target(reverseHexBytes(auxBlock.Target))
+        auxTargetBig, ok := auxTarget.ToBig()
+        if !ok {
+            log.Printf("Warning: Invalid aux target %s", auxBlock.Target)
+        } else {
+            aux = hash.Cmp(auxTargetBig) <= 0
         }
-        return primaryCandidate, shareDifficulty
     }
 
-    return shareValid, shareDifficulty
+    switch {
+    case primary && aux:
+        return dualCandidate, currentDiff
+    case aux:
+        return aux1Candidate, currentDiff
+    case primary:
+        return primaryCandidate, currentDiff
+    }
+
+    return shareValid, currentDiff
 }
 
 func getUpdatedDifficulty(minerAddr string, shareDiff float64) float64 {
@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+const (
+	defaultExtranonce2Size   = 4
+	defaultNtimeFutureWindow = 2 * time.Hour
+	poolVersionRollingMask   = 0x1fffe000 // BIP320
+	nonceHexLength           = 8
+	nonceTimeHexLength       = 8
+	versionBitsHexLength     = 8
+)
+
+// Rejections go back to the miner as stratum errors
+type shareRejection struct {
+	code   int
+	reason string
+}
+
+func (r shareRejection) Error() string {
+	return r.reason
+}
+
+var (
+	errJobNotFound        = shareRejection{stratumErrorJobNotFound, "Job not found"}
+	errMalformedShare     = shareRejection{stratumErrorOther, "Malformed share"}
+	errInvalidExtranonce2 = shareRejection{stratumErrorOther, "Invalid extranonce2 size"}
+	errInvalidNonceTime   = shareRejection{stratumErrorOther, "Invalid ntime"}
+	errNonceTimeTooOld    = shareRejection{stratumErrorOther, "ntime out of range: before mintime"}
+	errNonceTimeTooNew    = shareRejection{stratumErrorOther, "ntime out of range: too far in the future"}
+	errInvalidNonce       = shareRejection{stratumErrorOther, "Invalid nonce"}
+	errInvalidVersionBits = shareRejection{stratumErrorOther, "Invalid version bits"}
+)
+
+type submittedShare struct {
+	worker      string
+	jobID       string
+	extranonce2 string
+	nonceTime   string
+	nonce       string
+	versionBits *uint32 // Only with version rolling
+}
+
+func parseShare(share bitcoin.Work, block bitcoin.BitcoinBlock) (submittedShare, error) {
+	var parsed submittedShare
+
+	extranonce2Slot, _ := block.Extranonce2SubmissionSlot()
+	fields := []struct {
+		slot  int
+		value *string
+	}{
+		{0, &parsed.worker},
+		{1, &parsed.jobID},
+		{extranonce2Slot, &parsed.extranonce2},
+		{block.NonceTimeSubmissionSlot(), &parsed.nonceTime},
+		{block.NonceSubmissionSlot(), &parsed.nonce},
+	}
+	for _, field := range fields {
+		if field.slot >= len(share) {
+			return parsed, errMalformedShare
+		}
+		value, ok := share[field.slot].(string)
+		if !ok {
+			return parsed, errMalformedShare
+		}
+		*field.value = value
+	}
+
+	versionBitsSlot := block.NonceSubmissionSlot() + 1
+	if len(share) > versionBitsSlot {
+		versionBitsHex, ok := share[versionBitsSlot].(string)
+		if !ok || len(versionBitsHex) != versionBitsHexLength {
+			return parsed, errInvalidVersionBits
+		}
+		versionBits, err := strconv.ParseUint(versionBitsHex, 16, 32)
+		if err != nil {
+			return parsed, errInvalidVersionBits
+		}
+		bits := uint32(versionBits)
+		parsed.versionBits = &bits
+	}
+
+	return parsed, nil
+}
+
+func (p *PoolServer) validateShare(share submittedShare, template *bitcoin.Template, client *stratumClient) error {
+	if !isHexOfLength(share.extranonce2, p.extranonce2Size*2) {
+		return errInvalidExtranonce2
+	}
+
+	if !isHexOfLength(share.nonce, nonceHexLength) {
+		return errInvalidNonce
+	}
+
+	if !isHexOfLength(share.nonceTime, nonceTimeHexLength) {
+		return errInvalidNonceTime
+	}
+	nonceTime, err := strconv.ParseUint(share.nonceTime, 16, 32)
+	if err != nil {
+		return errInvalidNonceTime
+	}
+	if uint(nonceTime) < template.MinTime {
+		return errNonceTimeTooOld
+	}
+	if int64(nonceTime) > time.Now().Add(p.ntimeFutureWindow).Unix() {
+		return errNonceTimeTooNew
+	}
+
+	if share.versionBits != nil {
+		if client.versionRollingMask == 0 || *share.versionBits&^client.versionRollingMask != 0 {
+			return errInvalidVersionBits
+		}
+	}
+
+	return nil
+}
+
+func isHexOfLength(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -9,11 +10,14 @@ import (
 
 	"designs.capital/dogepool/bitcoin"
 	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
 )
 
 // Main INPUT
 func (p *PoolServer) fetchRpcBlockTemplatesAndCacheWork() error {
-	var block *bitcoin.BitcoinBlock
+	p.workLock.Lock()
+	defer p.workLock.Unlock()
+
 	var err error
 	template, auxblock, err := p.fetchAllBlockTemplatesFromRPC()
 	if err != nil {
@@ -28,27 +32,106 @@ func (p *PoolServer) fetchRpcBlockTemplatesAndCacheWork() error {
 		}
 	}
 
+	err = template.ApplyTransactionPolicy(p.transactionPolicy)
+	if err != nil {
+		return err
+	}
+
+	coinbasePayouts, err := p.makeCoinbasePayouts(template.CoinBaseValue)
+	if err != nil {
+		// Everything goes to the pool, the block will be credited to balances instead
+		log.Printf("Coinbase payouts unavailable for this job: %v", err)
+		coinbasePayouts = nil
+	}
+
+	err = p.cacheWork(&template, auxblock, coinbasePayouts)
+	if err != nil {
+		return err
+	}
+
+	if p.config.TemplateProposals.Enabled {
+		go func(block bitcoin.BitcoinBlock, source *rpc.RPCClient) {
+			err := p.proposeJob(block, source)
+			logOnError(err)
+		}(p.templates.GetPrimary(), p.templateSource)
+	}
+
+	return nil
+}
+
+// Miners move to the new previous block straight away instead of hashing stale work
+// until getblocktemplate returns.  Coinbase payouts are left out to keep it fast,
+// a block found on this job is credited to balances instead.
+func (p *PoolServer) cacheEmptyWork(previousBlockHash string) error {
+	p.workLock.Lock()
+	defer p.workLock.Unlock()
+
+	last := p.templates.GetPrimary()
+	if last.Template == nil {
+		return errors.New("no template to build an empty job from")
+	}
+	if last.Template.PrevBlockHash == previousBlockHash {
+		return errors.New("already working on " + previousBlockHash)
+	}
+
+	primaryName := p.config.GetPrimary()
+	var mwebTip *bitcoin.MwebTip
+	if last.Template.HasMweb() {
+		blockHex, err := p.rpcManagers[primaryName].GetActiveClient().GetBlockHex(context.Background(), previousBlockHash)
+		if err != nil {
+			return err
+		}
+		mwebTip, err = bitcoin.ParseMwebTip(blockHex)
+		if err != nil {
+			return err
+		}
+	}
+
+	template, err := last.Template.NextEmptyTemplate(bitcoin.GetChain(primaryName), previousBlockHash, mwebTip)
+	if err != nil {
+		return err
+	}
+
+	var auxblock *bitcoin.AuxBlock
+	if len(p.templates.AuxBlocks) > 0 && p.templates.GetAux1().Hash != "" {
+		auxblock = p.templates.GetAux1()
+	}
+
+	return p.cacheWork(template, auxblock, nil)
+}
+
+func (p *PoolServer) cacheWork(template *bitcoin.Template, auxblock *bitcoin.AuxBlock, coinbasePayouts []bitcoin.CoinbaseOutput) error {
 	auxillary := p.config.BlockSignature
 	if auxblock != nil {
 		mergedPOW := auxblock.GetWork()
 		auxillary = auxillary + hexStringToByteString(mergedPOW)
-
-		p.templates.AuxBlocks = []bitcoin.AuxBlock{*auxblock}
 	}
 
 	primaryName := p.config.GetPrimary()
 	// TODO this is chain/bitcoin specific
 	rewardPubScriptKey := p.GetPrimaryNode().RewardPubScriptKey
-	extranonceByteReservationLength := 8
+	extranonceByteReservationLength := extranonce1Length + p.extranonce2Size
 
-	block, p.workCache, err = bitcoin.GenerateWork(&template, auxblock,
+	block, work, err := bitcoin.GenerateWork(template, auxblock,
 		primaryName, auxillary, rewardPubScriptKey,
-		extranonceByteReservationLength)
+		extranonceByteReservationLength, coinbasePayouts)
 	if err != nil {
-		log.Print(err)
+		return err
 	}
 
+	last := p.templates.GetPrimary()
+	clean := last.Template == nil || last.Template.PrevBlockHash != template.PrevBlockHash
+
+	var registeredAux *bitcoin.AuxBlock
+	if auxblock != nil {
+		aux := *auxblock
+		registeredAux = &aux
+		p.templates.AuxBlocks = []bitcoin.AuxBlock{aux}
+	}
+	p.jobs.add(work[0].(string), job{block: *block, auxBlock: registeredAux}, clean)
+
 	p.templates.BitcoinBlock = *block
+	p.workCache = work
 
 	return nil
 }
@@ -64,30 +147,33 @@ var statusMap = map[int]string{
 }
 
 func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumClient) error {
-	primaryBlockTemplate := p.templates.GetPrimary()
-	if primaryBlockTemplate.Template == nil {
-		return errors.New("primary block template not yet set")
-	}
+	// Add debug logging
+	log.Printf("Received share from %s [%s]: %+v", client.ip, client.userAgent, share)
 
-	// Create a pointer to the block template
-	blockPtr := &bitcoin.BitcoinBlock{
-		Template:             primaryBlockTemplate.Template,
-		ReversePrevBlockHash: primaryBlockTemplate.ReversePrevBlockHash,
-		CoinbaseInitial:      primaryBlockTemplate.CoinbaseInitial,
-		CoinbaseFinal:        primaryBlockTemplate.CoinbaseFinal,
-		MerkleSteps:          primaryBlockTemplate.MerkleSteps,
-		Coinbase:             primaryBlockTemplate.Coinbase,
-		Header:               primaryBlockTemplate.Header,
-		Hash:                 primaryBlockTemplate.Hash,
-		Chain:                primaryBlockTemplate.Chain,
+	if len(share) < 2 {
+		return errMalformedShare
 	}
+	jobID, ok := share[1].(string)
+	if !ok {
+		return errMalformedShare
+	}
+	job, exists := p.jobs.get(jobID)
+	if !exists {
+		return errJobNotFound
+	}
+	primaryBlockTemplate := job.block
+	auxBlock := job.auxBlock
 
-	auxBlock := p.templates.GetAux1()
-
-	// Add debug logging
-	log.Printf("Received share from %s [%s]: %+v", client.ip, client.userAgent, share)
+	submitted, err := parseShare(share, primaryBlockTemplate)
+	if err != nil {
+		return err
+	}
+	err = p.validateShare(submitted, primaryBlockTemplate.Template, client)
+	if err != nil {
+		return err
+	}
 
-	workerString := share[0].(string)
+	workerString := submitted.worker
 	workerStringParts := strings.Split(workerString, ".")
 	if len(workerStringParts) < 2 {
 		return errors.New("invalid miner address")
@@ -96,10 +182,9 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 	rigID := workerStringParts[1]
 
 	primaryBlockHeight := primaryBlockTemplate.Template.Height
-	nonce := share[primaryBlockTemplate.NonceSubmissionSlot()].(string)
-	extranonce2Slot, _ := primaryBlockTemplate.Extranonce2SubmissionSlot()
-	extranonce2 := share[extranonce2Slot].(string)
-	nonceTime := share[primaryBlockTemplate.NonceTimeSubmissionSlot()].(string)
+	nonce := submitted.nonce
+	extranonce2 := submitted.extranonce2
+	nonceTime := submitted.nonceTime
 
 	// Add debug logging for share components
 	log.Printf("Share components - Height: %d, Nonce: %s, Extranonce2: %s, NonceTime: %s", 
@@ -107,14 +192,23 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 
 	extranonce := client.extranonce1 + extranonce2
 
-	header, err := primaryBlockTemplate.MakeHeader(extranonce, nonce, nonceTime)
+	// primaryBlockTemplate is this share's own copy of the job block
+	if submitted.versionBits != nil {
+		primaryBlockTemplate.RollVersion(*submitted.versionBits, client.versionRollingMask)
+	}
+	err = primaryBlockTemplate.MakeHeader(extranonce, nonce, nonceTime)
 	if err != nil {
 		log.Printf("Error making header: %v", err)
 		return err
 	}
 
 	// Add debug logging for header
-	log.Printf("Generated header: %s", header)
+	log.Printf("Generated header: %s", primaryBlockTemplate.HeaderHex())
+
+	err = p.hasher.hash(&primaryBlockTemplate)
+	if err != nil {
+		return err
+	}
 
 	// Use vardiff min_diff instead of pool_difficulty
 	difficulty := p.config.VarDiff.MinDiff
@@ -133,14 +227,6 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 
 	shareStatus, shareDifficulty := validateAndWeighShare(&primaryBlockTemplate, auxBlock, minerAddress)
 
-	// Now use shareStatus and err (if needed) after they are defined
-	if shareStatus >= aux1Candidate {
-		err := p.submitBlockToChain(blockPtr)
-		if err != nil {
-			return err
-		}
-	}
-
 	// Add debug logging for validation results
 	log.Printf("Share validation - Status: %d, Difficulty: %f, Current Difficulty: %f", 
                shareStatus, shareDifficulty, currentDiff)
@@ -185,7 +271,7 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 	}
 
 	statusReadable := statusMap[shareStatus]
-	successStatus := 0
+	successStatus := shareInvalid
 
 	m = "%v block candidate for block %v from %v [%v]"
 	m = fmt.Sprintf(m, statusReadable, heightMessage, client.ip, rigID)
@@ -200,18 +286,39 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 		Source:               "",
 	}
 
-	aux1Name := p.config.GetAux1()
-	if aux1Name != "" && shareStatus >= aux1Candidate {
-		err = p.submitAuxBlock(primaryBlockTemplate, *auxBlock)
+	if shareStatus == dualCandidate || shareStatus == primaryCandidate {
+		results, err := p.submitBlockToChain(&primaryBlockTemplate)
 		if err != nil {
-			// Try to submit on different node
-			err = p.rpcManagers[p.config.GetAux1()].CheckAndRecoverRPCs()
+			log.Println(err)
+		} else {
+			found.Chain = p.config.GetPrimary()
+			found.Created = time.Now()
+			found.PaidInCoinbase = primaryBlockTemplate.PaysOutInCoinbase()
+			found.PoolRewardsInCoinbase = primaryBlockTemplate.PaysPoolRewardsInCoinbase()
+			found.Submissions = results.String()
+			found.Hash, err = primaryBlockTemplate.HeaderHashed()
 			if err != nil {
-				return err
+				log.Println(err)
+			}
+			found.NetworkDifficulty = blockDifficulty
+			found.BlockHeight = primaryBlockHeight
+			found.TransactionConfirmationData, err = primaryBlockTemplate.CoinbaseHashed()
+			if err != nil {
+				log.Println(err)
 			}
-			err = p.submitBlockToChain(&primaryBlockTemplate)
+
+			err = persistence.Blocks.Insert(found)
+			if err != nil {
+				log.Println(err)
+			}
+
+			successStatus = primaryCandidate
 		}
+	}
 
+	aux1Name := p.config.GetAux1()
+	if aux1Name != "" && (shareStatus == dualCandidate || shareStatus == aux1Candidate) {
+		results, err := p.submitAuxBlock(primaryBlockTemplate, *auxBlock)
 		if err != nil {
 			log.Println(err)
 		} else {
@@ -222,6 +329,9 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 
 			found.Chain = aux1Name
 			found.Created = time.Now()
+			found.PaidInCoinbase = false
+			found.PoolRewardsInCoinbase = false
+			found.Submissions = results.String()
 			found.Hash = auxBlock.Hash
 			found.NetworkDifficulty = aux1Difficulty
 			found.BlockHeight = uint(auxBlock.Height)
@@ -233,61 +343,19 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 				log.Println(err)
 			}
 
-			successStatus = aux1Candidate
-		}
-	}
-
-	// Fix type conversion for BitcoinBlock
-	primaryBlockTemplatePtr := &primaryBlockTemplate
-
-	// Update all submitBlockToChain calls to use the pointer
-	if shareStatus >= aux1Candidate {
-	    err = p.submitBlockToChain(primaryBlockTemplatePtr)
-	    if err != nil {
-	        return err
-	    }
-	}
-
-	if shareStatus == dualCandidate || shareStatus == primaryCandidate {
-		err = p.submitBlockToChain(blockPtr)
-		if err != nil {
-			// Try to submit on different node
-			err = p.rpcManagers[p.config.GetPrimary()].CheckAndRecoverRPCs()
-			if err != nil {
-				return err
-			}
-			err = p.submitBlockToChain(blockPtr)
-		}
-
-		if err != nil {
-			return err
-		} else {
-			found.Chain = p.config.GetPrimary()
-			found.Created = time.Now()
-			found.Hash, err = primaryBlockTemplate.HeaderHashed()
-			if err != nil {
-				log.Println(err)
-			}
-			found.NetworkDifficulty = blockDifficulty
-			found.BlockHeight = primaryBlockHeight
-			found.TransactionConfirmationData, err = primaryBlockTemplate.CoinbaseHashed()
-			if err != nil {
-				log.Println(err)
-			}
-
-			err = persistence.Blocks.Insert(found)
-			if err != nil {
-				log.Println(err)
-			}
-			found.Chain = ""
-			if successStatus == aux1Candidate {
+			if successStatus == primaryCandidate {
 				successStatus = dualCandidate
 			} else {
-				successStatus = primaryCandidate
+				successStatus = aux1Candidate
 			}
 		}
 	}
 
+	if successStatus == shareInvalid {
+		m = "no node accepted %v block candidate for block %v from %v [%v]"
+		return fmt.Errorf(m, statusReadable, heightMessage, client.ip, rigID)
+	}
+
 	statusReadable = statusMap[successStatus]
 
 	log.Printf("✅  Successful %v submission of block %v from: %v [%v]", statusReadable, heightMessage, client.ip, rigID)
@@ -301,16 +369,20 @@ func (pool *PoolServer) generateWorkFromCache(refresh bool) (bitcoin.Work, error
 	return work, nil
 }
 
-// Add submitAuxBlock method
-func (p *PoolServer) submitAuxBlock(primaryBlock bitcoin.BitcoinBlock, aux1Block bitcoin.AuxBlock) error {
-    auxpow := bitcoin.MakeAuxPow(primaryBlock)
-    success, err := p.GetAux1Node().RPC.SubmitAuxBlock(aux1Block.Hash, auxpow.Serialize())
-    if !success {
-        nodeName := p.GetAux1Node().ChainName
-        m := fmt.Sprintf("⚠️  %v node failed to submit aux block: %v", nodeName, err.Error())
-        return errors.New(m)
-    }
-    return err
+func (p *PoolServer) submitAuxBlock(primaryBlock bitcoin.BitcoinBlock, aux1Block bitcoin.AuxBlock) (submissionResults, error) {
+	auxpow := bitcoin.MakeAuxPow(primaryBlock)
+	auxpowHex := auxpow.Serialize()
+
+	aux1Name := p.config.GetAux1()
+	results := p.submitToAllNodes(aux1Name, func(ctx context.Context, client *rpc.RPCClient) (bool, error) {
+		return client.SubmitAuxBlock(ctx, aux1Block.Hash, auxpowHex)
+	})
+	if !results.accepted() {
+		return results, fmt.Errorf("⚠️  no %v node accepted aux block %v: %v", aux1Name, aux1Block.Height, results)
+	}
+
+	log.Printf("Successfully submitted %v aux block to chain: %v %v", aux1Name, aux1Block.Height, results)
+	return results, nil
 }
 
 // Add CheckAndRecoverRPCs method
@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// One call of a JSON-RPC batch, Result is unmarshalled into and Err set per call
+type BatchCall struct {
+	Method string
+	Params []interface{}
+	Result interface{}
+	Err    error
+}
+
+// Sends every call in one round trip. The returned error is for the batch as a whole,
+// each call's own error is in its Err.
+func (r *RPCClient) Batch(ctx context.Context, calls []BatchCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	requests := make([]rpcRequest, len(calls))
+	callByID := make(map[uint64]*BatchCall, len(calls))
+	for i := range calls {
+		requests[i] = r.newRequest(calls[i].Method, calls[i].Params)
+		callByID[requests[i].ID] = &calls[i]
+	}
+
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return err
+	}
+
+	var responses []rpcResponse
+	start := time.Now()
+	status, err := r.postAndDecode(ctx, body, true, &responses)
+	latency := time.Since(start)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("HTTP %v for batch of %v calls", status, len(calls))
+	}
+	if err != nil {
+		for i := range calls {
+			calls[i].Err = err
+			r.metrics.record(calls[i].Method, latency, true)
+		}
+		return err
+	}
+
+	for _, response := range responses {
+		call, exists := callByID[response.ID]
+		if !exists {
+			continue
+		}
+		delete(callByID, response.ID)
+		if response.Error != nil {
+			call.Err = response.Error
+		} else if call.Result != nil {
+			call.Err = json.Unmarshal(response.Result, call.Result)
+		}
+	}
+	for id, call := range callByID {
+		call.Err = fmt.Errorf("RPC %v: no response for request id %v", call.Method, id)
+	}
+
+	// The batch latency is what each call cost us
+	for i := range calls {
+		r.metrics.record(calls[i].Method, latency, calls[i].Err != nil)
+	}
+
+	return nil
+}
+
+func (r *RPCClient) GetBlocksByHash(ctx context.Context, hashes []string) ([]*GetBlockReply, []error) {
+	calls := make([]BatchCall, len(hashes))
+	blocks := make([]*GetBlockReply, len(hashes))
+	for i, hash := range hashes {
+		blocks[i] = &GetBlockReply{}
+		calls[i] = BatchCall{Method: "getblock", Params: []interface{}{hash}, Result: blocks[i]}
+	}
+
+	return blocks, batchErrors(r.Batch(ctx, calls), calls)
+}
+
+func (r *RPCClient) GetTransactions(ctx context.Context, transactionIDs []string) ([]Transaction, []error) {
+	calls := make([]BatchCall, len(transactionIDs))
+	transactions := make([]Transaction, len(transactionIDs))
+	for i, transactionID := range transactionIDs {
+		calls[i] = BatchCall{Method: "gettransaction", Params: []interface{}{transactionID}, Result: &transactions[i]}
+	}
+
+	return transactions, batchErrors(r.Batch(ctx, calls), calls)
+}
+
+type TxOut struct {
+	Confirmations int64   `json:"confirmations"` // 0 for an output of a mempool transaction
+	Value         float64 `json:"value"`
+}
+
+// nil for outputs that are spent, counting spends in the mempool, or never existed
+func (r *RPCClient) GetTxOuts(ctx context.Context, outpoints []DecodedInput) ([]*TxOut, []error) {
+	calls := make([]BatchCall, len(outpoints))
+	outputs := make([]*TxOut, len(outpoints))
+	includeMempool := true
+	for i, outpoint := range outpoints {
+		params := []interface{}{outpoint.TransactionID, outpoint.Output, includeMempool}
+		calls[i] = BatchCall{Method: "gettxout", Params: params, Result: &outputs[i]}
+	}
+
+	return outputs, batchErrors(r.Batch(ctx, calls), calls)
+}
+
+func batchErrors(batchErr error, calls []BatchCall) []error {
+	errs := make([]error, len(calls))
+	for i, call := range calls {
+		errs[i] = call.Err
+		if errs[i] == nil {
+			errs[i] = batchErr
+		}
+	}
+	return errs
+}
@@ -6,4 +6,11 @@ type Config struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Timeout  string `json:"timeout"`
+	// Credential sources that keep the password out of config.json, see credentials.go
+	CookieFile   string `json:"cookie_file"`
+	UsernameEnv  string `json:"username_env"`
+	PasswordEnv  string `json:"password_env"`
+	PasswordFile string `json:"password_file"`
+	// Multi algorithm chains (DigiByte) template and report difficulty per algorithm
+	Algorithm string `json:"algorithm"`
 }
@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Where a node's RPC username and password come from, in order of precedence:
+// the node's .cookie file, a password file, environment variables, then the static config
+type credentials struct {
+	sync.RWMutex
+	username   string
+	password   string
+	cookieFile string
+}
+
+func makeCredentials(node Config) (*credentials, error) {
+	c := &credentials{
+		username:   node.Username,
+		password:   node.Password,
+		cookieFile: node.CookieFile,
+	}
+
+	if node.UsernameEnv != "" {
+		c.username = os.Getenv(node.UsernameEnv)
+	}
+	if node.PasswordEnv != "" {
+		c.password = os.Getenv(node.PasswordEnv)
+	}
+	if node.PasswordFile != "" {
+		password, err := os.ReadFile(node.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		c.password = strings.TrimSpace(string(password))
+	}
+
+	if c.cookieFile != "" {
+		return c, c.readCookie()
+	}
+	return c, nil
+}
+
+func (c *credentials) get() (string, string) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.username, c.password
+}
+
+func (c *credentials) fromCookie() bool {
+	return c.cookieFile != ""
+}
+
+// The node rewrites its cookie on every restart
+func (c *credentials) readCookie() error {
+	cookie, err := os.ReadFile(c.cookieFile)
+	if err != nil {
+		return err
+	}
+	username, password, found := strings.Cut(strings.TrimSpace(string(cookie)), ":")
+	if !found {
+		return errors.New("malformed RPC cookie file: " + c.cookieFile)
+	}
+
+	c.Lock()
+	c.username = username
+	c.password = password
+	c.Unlock()
+	return nil
+}
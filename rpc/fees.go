@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"context"
+	"sort"
+)
+
+// How a payout pays its network fee
+type FeeOptions struct {
+	FeeRate     float64 // sat/vB, 0 leaves it to the wallet's estimate
+	SubtractFee bool    // Split the fee over the recipients instead of the pool paying it
+}
+
+// Legacy wallets take fee rates in coins per kB
+func (o FeeOptions) coinsPerKB() float64 {
+	return o.FeeRate * 1000 / 1e8
+}
+
+// Every output pays its share, outputs are sent as a JSON object so they're in key order
+func (o FeeOptions) subtractFeeFromOutputs(outputs map[string]float64) []int {
+	indexes := []int{}
+	if o.SubtractFee {
+		for i := 0; i < len(outputs); i++ {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func (o FeeOptions) subtractFeeFrom(outputs map[string]float64) []string {
+	addresses := []string{}
+	if o.SubtractFee {
+		for address := range outputs {
+			addresses = append(addresses, address)
+		}
+		sort.Strings(addresses)
+	}
+	return addresses
+}
+
+// For wallets whose sendmany takes no fee rate.  The rate is wallet-wide, so the
+// previous one is returned for RestoreTransactionFee once the payout is sent.
+func (r *RPCClient) SetTransactionFee(ctx context.Context, options FeeOptions) (float64, error) {
+	var info struct {
+		PayTxFee float64 `json:"paytxfee"` // Coins per kB, 0 for the wallet's estimate
+	}
+	err := r.Call(ctx, "getwalletinfo", nil, &info)
+	if err != nil {
+		return 0, err
+	}
+	return info.PayTxFee, r.Call(ctx, "settxfee", []interface{}{options.coinsPerKB()}, nil)
+}
+
+func (r *RPCClient) RestoreTransactionFee(ctx context.Context, previous float64) error {
+	return r.Call(ctx, "settxfee", []interface{}{previous}, nil)
+}
@@ -1,9 +1,114 @@
 package rpc
 
-func (r *RPCClient) Check() bool {
-	_, err := r.GetBlockTemplate()
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// What makes a reachable node unhealthy
+type HealthThresholds struct {
+	MinPeers      int64         // 0 disables the check
+	MaxTipAge     time.Duration // 0 disables the check
+	MaxHeaderLag  int64         // 0 requires the blocks to be at the best header, negative disables the check
+	RequireWallet bool
+}
+
+type HealthReport struct {
+	Reachable            bool
+	Synced               bool
+	InitialBlockDownload bool
+	Peers                int64
+	Height               int64
+	BestBlockHash        string
+	Latency              time.Duration // Of the chain, peers and wallet batch
+	TipAge               time.Duration
+	Warnings             string
+	WalletAvailable      bool
+	Healthy              bool
+	Problems             []string
+	Checked              time.Time
+}
+
+type healthBlockChainInfo struct {
+	Blocks               int64           `json:"blocks"`
+	Headers              int64           `json:"headers"`
+	BestBlockHash        string          `json:"bestblockhash"`
+	InitialBlockDownload bool            `json:"initialblockdownload"`
+	Warnings             json.RawMessage `json:"warnings"` // A string, or a list since Bitcoin Core 28
+}
+
+type healthBlockHeader struct {
+	Time int64 `json:"time"`
+}
+
+// Two round trips: chain, peers and wallet in one batch, then the tip's header for its age
+func (r *RPCClient) CheckHealth(ctx context.Context, thresholds HealthThresholds) HealthReport {
+	report := HealthReport{Checked: time.Now()}
+
+	var info healthBlockChainInfo
+	var walletInfo json.RawMessage
+	calls := []BatchCall{
+		{Method: "getblockchaininfo", Result: &info},
+		{Method: "getconnectioncount", Result: &report.Peers},
+		{Method: "getwalletinfo", Result: &walletInfo},
+	}
+	start := time.Now()
+	err := r.Batch(ctx, calls)
+	report.Latency = time.Since(start)
+	if err == nil {
+		err = calls[0].Err
+	}
+	if err != nil {
+		report.Problems = append(report.Problems, "unreachable: "+err.Error())
+		return report
+	}
+	report.Reachable = true
+	report.Height = info.Blocks
+	report.BestBlockHash = info.BestBlockHash
+	report.InitialBlockDownload = info.InitialBlockDownload
+	headerLagged := thresholds.MaxHeaderLag >= 0 && info.Headers-info.Blocks > thresholds.MaxHeaderLag
+	report.Synced = !info.InitialBlockDownload && !headerLagged
+	report.Warnings = warningsText(info.Warnings)
+	report.WalletAvailable = calls[2].Err == nil
+
+	if calls[1].Err != nil {
+		report.Problems = append(report.Problems, "peer count: "+calls[1].Err.Error())
+	}
+
+	var header healthBlockHeader
+	err = r.Call(ctx, "getblockheader", []interface{}{info.BestBlockHash}, &header)
 	if err != nil {
-		return false
+		report.Problems = append(report.Problems, "tip header: "+err.Error())
+	} else {
+		report.TipAge = time.Since(time.Unix(header.Time, 0)).Truncate(time.Second)
+	}
+
+	if !report.Synced {
+		report.Problems = append(report.Problems, fmt.Sprintf("not synced: %v of %v blocks", info.Blocks, info.Headers))
+	}
+	if report.Peers < thresholds.MinPeers {
+		report.Problems = append(report.Problems, fmt.Sprintf("%v peers, need %v", report.Peers, thresholds.MinPeers))
+	}
+	if thresholds.MaxTipAge > 0 && report.TipAge > thresholds.MaxTipAge {
+		report.Problems = append(report.Problems, fmt.Sprintf("tip is %v old", report.TipAge))
+	}
+	if thresholds.RequireWallet && !report.WalletAvailable {
+		report.Problems = append(report.Problems, "wallet unavailable")
+	}
+
+	report.Healthy = len(report.Problems) == 0
+	return report
+}
+
+func warningsText(raw json.RawMessage) string {
+	var warning string
+	if json.Unmarshal(raw, &warning) == nil {
+		return warning
 	}
-	return true
+	var warnings []string
+	json.Unmarshal(raw, &warnings)
+	return strings.Join(warnings, "; ")
 }
@@ -1,26 +1,68 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"log"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	EventFailover = "failover"
+	EventFailback = "failback"
+)
+
+// Sent to subscribers whenever the active node of a chain changes
+type Event struct {
+	Chain string
+	Kind  string
+	From  string
+	To    string
+	Time  time.Time
+}
+
+type NodeHealth struct {
+	Name   string
+	Active bool
+	HealthReport
+}
+
+// One manager per chain is shared by the pool and payouts so both always use the same node
 type Manager struct {
+	sync.RWMutex
 	chainName            string
 	activeIndex          int
 	clients              []*RPCClient
+	walletClient         *RPCClient
+	health               []NodeHealth
+	thresholds           HealthThresholds
+	failedOverAt         time.Time
+	healthInterval       time.Duration
 	primaryCheckInterval time.Duration
+	subscribers          []chan Event
+	startOnce            sync.Once
 }
 
-func MakeRPCManager(chainName string, nodes []Config, returnToPrimaryAfter string) Manager {
-	m := Manager{}
+func MakeRPCManager(chainName string, nodes []Config, thresholds HealthThresholds, healthInterval, returnToPrimaryAfter string) *Manager {
+	m := &Manager{}
 	m.chainName = chainName
+	m.thresholds = thresholds
 	m.clients = make([]*RPCClient, len(nodes))
+	m.health = make([]NodeHealth, len(nodes))
 	for i, node := range nodes {
-		m.clients[i] = NewRPCClient(node.Name, node.URL, node.Username, node.Password, node.Timeout)
+		m.clients[i] = NewRPCClient(node)
+		// Assume healthy until the first check says otherwise
+		m.health[i] = NodeHealth{Name: node.Name}
+		m.health[i].Healthy = true
 	}
+	m.health[0].Active = true
 	var err error
+	m.healthInterval, err = time.ParseDuration(healthInterval)
+	if err != nil {
+		panic(err)
+	}
 	m.primaryCheckInterval, err = time.ParseDuration(returnToPrimaryAfter)
 	if err != nil {
 		panic(err)
@@ -28,68 +70,222 @@ func MakeRPCManager(chainName string, nodes []Config, returnToPrimaryAfter strin
 	return m
 }
 
+// Starts the chain's single health loop, later calls do nothing
+func (m *Manager) Start() {
+	m.startOnce.Do(func() {
+		go func() {
+			for {
+				time.Sleep(m.healthInterval)
+				m.checkAllNodes()
+				m.recover()
+			}
+		}()
+	})
+}
+
+// Failover and failback events, slow subscribers miss events rather than block the manager
+func (m *Manager) Subscribe() <-chan Event {
+	m.Lock()
+	defer m.Unlock()
+	events := make(chan Event, 16)
+	m.subscribers = append(m.subscribers, events)
+	return events
+}
+
 func (manager *Manager) GetActiveClient() *RPCClient {
+	manager.RLock()
+	defer manager.RUnlock()
 	return manager.clients[manager.activeIndex]
 }
 
-func (manager *Manager) CheckAndRecoverRPCs() error {
-	if manager.GetActiveClient().Check() {
-		return nil
+// Only one node holds the wallet with the coinbase rewards, it stays out of the failover set
+func (manager *Manager) SetWalletClient(client *RPCClient) {
+	manager.Lock()
+	defer manager.Unlock()
+	manager.walletClient = client
+}
+
+// The wallet node when one is configured, else the active node
+func (manager *Manager) GetWalletClient() *RPCClient {
+	manager.RLock()
+	defer manager.RUnlock()
+	if manager.walletClient != nil {
+		return manager.walletClient
 	}
-	err := manager.FindHealthyNode()
-	if err != nil {
-		return err
-	}
-	// Launch loop to eventually get us back to the primary node
-	go func() {
-		for {
-			time.Sleep(manager.primaryCheckInterval)
-			if manager.CheckPrimary() {
-				manager.RestorePrimary()
-				return
-			}
-		}
-	}()
+	return manager.clients[manager.activeIndex]
+}
 
+// The wallet or failover node with the given URL, nil when it's no longer configured
+func (manager *Manager) GetClientByURL(url string) *RPCClient {
+	manager.RLock()
+	defer manager.RUnlock()
+	if manager.walletClient != nil && manager.walletClient.NodeUrl == url {
+		return manager.walletClient
+	}
+	for _, client := range manager.clients {
+		if client.NodeUrl == url {
+			return client
+		}
+	}
 	return nil
 }
 
-func (m *Manager) RestorePrimary() {
-	m.activeIndex = 0
+// Every configured node for the chain, in config order
+func (manager *Manager) GetClients() []*RPCClient {
+	return manager.clients
 }
 
-func (m *Manager) FindHealthyNode() error {
-	nodesLength := len(m.clients)
-	nodesChecked := 0
-	nowHealthy := false
-	for !nowHealthy {
-		nodesChecked++
-		if nodesChecked > nodesLength {
-			return errors.New("no healthy " + m.chainName + " nodes!")
+// The next node after the active one, or the active one when there's only one
+func (manager *Manager) GetSecondaryClient() *RPCClient {
+	manager.RLock()
+	defer manager.RUnlock()
+	return manager.clients[(manager.activeIndex+1)%len(manager.clients)]
+}
+
+// Every node that passed its last health check, or the active node when none did
+func (m *Manager) GetHealthyClients() []*RPCClient {
+	m.RLock()
+	defer m.RUnlock()
+	var healthy []*RPCClient
+	for i, client := range m.clients {
+		if m.health[i].Healthy {
+			healthy = append(healthy, client)
 		}
-		m.nextNode()
-		nowHealthy = m.checkActiveNodeHealth()
 	}
-	log.Printf("Now on node: %v\n", m.activeIndex)
-	return nil
+	if len(healthy) == 0 {
+		return []*RPCClient{m.clients[m.activeIndex]}
+	}
+	return healthy
 }
 
-func (m *Manager) CheckPrimary() bool {
-	return m.clients[0].Check()
+// A healthy node other than exclude, in config order, or nil when there's none
+func (m *Manager) GetHealthyClientExcept(exclude *RPCClient) *RPCClient {
+	m.RLock()
+	defer m.RUnlock()
+	for i, client := range m.clients {
+		if client != exclude && m.health[i].Healthy {
+			return client
+		}
+	}
+	return nil
 }
 
 func (m *Manager) GetIndex() int {
+	m.RLock()
+	defer m.RUnlock()
 	return m.activeIndex
 }
 
-func (m *Manager) checkActiveNodeHealth() bool {
-	return m.GetActiveClient().Check()
+// The latest health report of every node, in config order
+func (m *Manager) Health() []NodeHealth {
+	m.RLock()
+	defer m.RUnlock()
+	health := make([]NodeHealth, len(m.health))
+	copy(health, m.health)
+	return health
 }
 
-func (m *Manager) nextNode() {
-	if m.activeIndex < len(m.clients)-1 {
-		m.activeIndex++
-	} else {
-		m.activeIndex = 0
+// Checks the active node now and fails over when it's down.
+// Returning to the primary node is left to the health loop.
+func (manager *Manager) CheckAndRecoverRPCs() error {
+	index := manager.GetIndex()
+	if manager.checkNode(index) {
+		return nil
+	}
+	return manager.FindHealthyNode()
+}
+
+func (m *Manager) FindHealthyNode() error {
+	start := m.GetIndex()
+	for offset := 1; offset <= len(m.clients); offset++ {
+		index := (start + offset) % len(m.clients)
+		if m.checkNode(index) {
+			m.switchTo(index, EventFailover)
+			return nil
+		}
+	}
+	return errors.New("no healthy " + m.chainName + " nodes!")
+}
+
+func (m *Manager) checkNode(index int) bool {
+	report := m.clients[index].CheckHealth(context.Background(), m.thresholds)
+	if !report.Healthy {
+		log.Printf("%v node %v is unhealthy: %v", m.chainName, m.health[index].Name, strings.Join(report.Problems, ", "))
+	}
+	m.Lock()
+	m.health[index].HealthReport = report
+	m.Unlock()
+	return report.Healthy
+}
+
+func (m *Manager) checkAllNodes() {
+	for i := range m.clients {
+		m.checkNode(i)
+	}
+}
+
+// Fails over off an unhealthy active node, and back to the primary node once
+// it has been healthy for the return to primary interval
+func (m *Manager) recover() {
+	m.RLock()
+	activeHealthy := m.health[m.activeIndex].Healthy
+	returnToPrimary := m.activeIndex != 0 && m.health[0].Healthy &&
+		time.Since(m.failedOverAt) >= m.primaryCheckInterval
+	m.RUnlock()
+
+	if !activeHealthy {
+		m.RLock()
+		start := m.activeIndex
+		next := -1
+		for offset := 1; offset < len(m.clients); offset++ {
+			index := (start + offset) % len(m.clients)
+			if m.health[index].Healthy {
+				next = index
+				break
+			}
+		}
+		m.RUnlock()
+
+		if next < 0 {
+			log.Printf("No healthy %v nodes!", m.chainName)
+			return
+		}
+		m.switchTo(next, EventFailover)
+		return
+	}
+
+	if returnToPrimary {
+		m.switchTo(0, EventFailback)
+	}
+}
+
+func (m *Manager) switchTo(index int, kind string) {
+	m.Lock()
+	defer m.Unlock()
+	if index == m.activeIndex {
+		return
+	}
+
+	event := Event{
+		Chain: m.chainName,
+		Kind:  kind,
+		From:  m.health[m.activeIndex].Name,
+		To:    m.health[index].Name,
+		Time:  time.Now(),
+	}
+
+	m.health[m.activeIndex].Active = false
+	m.health[index].Active = true
+	m.activeIndex = index
+	if kind == EventFailover {
+		m.failedOverAt = event.Time
+	}
+	log.Printf("%v %v from node %v to node %v", m.chainName, kind, event.From, event.To)
+
+	for _, subscriber := range m.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
 	}
 }
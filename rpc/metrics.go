@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+)
+
+type MethodStats struct {
+	Calls   uint64
+	Errors  uint64
+	Latency time.Duration // Total, divide by Calls for the average
+}
+
+func (s MethodStats) AverageLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Calls)
+}
+
+type methodMetrics struct {
+	sync.Mutex
+	methods map[string]MethodStats
+}
+
+func (m *methodMetrics) record(method string, latency time.Duration, failed bool) {
+	m.Lock()
+	defer m.Unlock()
+	if m.methods == nil {
+		m.methods = make(map[string]MethodStats)
+	}
+	stats := m.methods[method]
+	stats.Calls++
+	stats.Latency += latency
+	if failed {
+		stats.Errors++
+	}
+	m.methods[method] = stats
+}
+
+// Counters per RPC method since start
+func (r *RPCClient) Metrics() map[string]MethodStats {
+	r.metrics.Lock()
+	defer r.metrics.Unlock()
+	metrics := make(map[string]MethodStats, len(r.metrics.methods))
+	for method, stats := range r.metrics.methods {
+		metrics[method] = stats
+	}
+	return metrics
+}
@@ -2,139 +2,207 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type RPCClient struct {
-	NodeUrl string
-	Name    string
-	client  *http.Client
+	NodeUrl     string // Never holds credentials, safe to log
+	Name        string
+	Algorithm   string
+	client      *http.Client
+	credentials *credentials
+	nextID      atomic.Uint64
+	metrics     methodMetrics
 }
 
-func NewRPCClient(name, rpcURL, rpcUser, rpcPassword, timeout string) *RPCClient {
-	urlParts := strings.Split(rpcURL, "://")
+func NewRPCClient(node Config) *RPCClient {
 	rpcClient := &RPCClient{
-		Name:    name,
-		NodeUrl: urlParts[0] + "://" + rpcUser + ":" + rpcPassword + "@" + urlParts[1]}
+		Name:      node.Name,
+		NodeUrl:   node.URL,
+		Algorithm: node.Algorithm,
+	}
 
-	timeOutIntv, err := time.ParseDuration(timeout)
+	timeOutIntv, err := time.ParseDuration(node.Timeout)
 	if err != nil {
-		panic("util: Can'blockTemplate parse duration `" + timeout + "`: " + err.Error())
+		panic("util: Can'blockTemplate parse duration `" + node.Timeout + "`: " + err.Error())
 	}
 
 	rpcClient.client = &http.Client{
 		Timeout: timeOutIntv,
 	}
 
+	rpcClient.credentials, err = makeCredentials(node)
+	if err != nil {
+		panic("rpc: can't load credentials for node " + node.Name + ": " + err.Error())
+	}
+
 	return rpcClient
 }
 
+type rpcRequest struct {
+	ID             uint64        `json:"id"`
+	JsonRPCVersion string        `json:"jsonrpc"`
+	Method         string        `json:"method"`
+	Parameters     []interface{} `json:"params"`
+}
+
 type rpcResponse struct {
 	Result json.RawMessage `json:"result"`
-	Error  rpcError        `json:"error"`
-	ID     int             `json:"id"`
+	Error  *Error          `json:"error"`
+	ID     uint64          `json:"id"`
 }
 
-type rpcError struct {
+// The node's own JSON-RPC error, callers can errors.As for it
+type Error struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
 
-func (r *RPCClient) doRequest(method string, params []interface{}) (rpcResponse, int, error) {
-	type rpcRequest struct {
-		ID             int           `json:"id"`
-		JsonRPCVersion string        `json:"jsonrpc"`
-		Method         string        `json:"method"`
-		Parameters     []interface{} `json:"params"`
-	}
+func (e *Error) Error() string {
+	return fmt.Sprintf("RPC error %v: %v", e.Code, e.Message)
+}
 
-	var jsonStr rpcRequest
-	jsonStr.ID = 1219
-	jsonStr.JsonRPCVersion = "2.0"
-	jsonStr.Method = method
-	jsonStr.Parameters = params
+func (r *RPCClient) newRequest(method string, params []interface{}) rpcRequest {
+	return rpcRequest{
+		ID:             r.nextID.Add(1),
+		JsonRPCVersion: "2.0",
+		Method:         method,
+		Parameters:     params,
+	}
+}
 
+func (r *RPCClient) doRequest(ctx context.Context, method string, params []interface{}) (rpcResponse, int, error) {
 	var rpcResp rpcResponse
 
-	s, err := json.Marshal(jsonStr)
+	request := r.newRequest(method, params)
+	s, err := json.Marshal(request)
 	if err != nil {
 		return rpcResp, 0, err
 	}
 
-	req, err := http.NewRequest("POST", r.NodeUrl, bytes.NewBuffer(s))
-	if err != nil {
-		return rpcResp, 0, err
+	start := time.Now()
+	status, err := r.postAndDecode(ctx, s, params != nil, &rpcResp)
+	if err == nil && status == http.StatusOK && rpcResp.ID != request.ID {
+		err = fmt.Errorf("RPC %v: response id %v does not match request id %v", method, rpcResp.ID, request.ID)
 	}
-	req.Header.Add("accept", "application/json")
+	r.metrics.record(method, time.Since(start), err != nil || status != http.StatusOK || rpcResp.Error != nil)
 
-	if params != nil {
-		req.Header.Add("Content-Type", "application/json")
-	}
+	return rpcResp, status, err
+}
 
-	resp, err := r.client.Do(req)
+// Posts the body, re-reading the node's cookie once on 401, and decodes the reply into response
+func (r *RPCClient) postAndDecode(ctx context.Context, body []byte, hasParams bool, response interface{}) (int, error) {
+	resp, err := r.post(ctx, body, hasParams)
 	if err != nil {
-		return rpcResp, 0, err
+		return 0, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.credentials.fromCookie() {
+		// The node restarted and wrote a new cookie
+		resp.Body.Close()
+		err = r.credentials.readCookie()
+		if err != nil {
+			return 0, err
+		}
+		resp, err = r.post(ctx, body, hasParams)
+		if err != nil {
+			return 0, err
+		}
 	}
 	defer resp.Body.Close()
 
-	json.NewDecoder(resp.Body).Decode(&rpcResp)
+	err = json.NewDecoder(resp.Body).Decode(response)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("RPC: can't decode response: %w", err)
+	}
 
-	return rpcResp, resp.StatusCode, nil
+	// Error statuses may not carry a JSON body, callers report the status
+	return resp.StatusCode, nil
 }
 
-func (r *RPCClient) GetPeerCount() (int64, error) { // getconnectioncount
-	var n int64
-	resp, status, err := r.doRequest("getconnectioncount", nil)
+// Context aware call, node errors come back as *Error
+func (r *RPCClient) Call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	resp, status, err := r.doRequest(ctx, method, params)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	if status != http.StatusOK || resp.Error != nil {
+		return handleHttpError(resp, status)
+	}
+	if result == nil {
+		return nil
 	}
+	return json.Unmarshal(resp.Result, result)
+}
 
-	if status != 200 {
-		return 0, handleHttpError(resp, status)
+func (r *RPCClient) post(ctx context.Context, body []byte, hasParams bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", r.NodeUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Add("accept", "application/json")
 
-	json.Unmarshal(resp.Result, &n)
+	if hasParams {
+		req.Header.Add("Content-Type", "application/json")
+	}
 
-	return n, nil
+	username, password := r.credentials.get()
+	req.SetBasicAuth(username, password)
+
+	return r.client.Do(req)
 }
 
-func (r *RPCClient) GetBlockTemplate() (json.RawMessage, error) {
+func (r *RPCClient) GetPeerCount(ctx context.Context) (int64, error) { // getconnectioncount
+	var n int64
+	err := r.Call(ctx, "getconnectioncount", nil, &n)
+	return n, err
+}
+
+func (r *RPCClient) GetBlockTemplate(ctx context.Context) (json.RawMessage, error) {
 	params := make([]interface{}, 1)
 	rules := make(map[string][]string)
 	rules["rules"] = make([]string, 2)
 	rules["rules"][0] = "mweb"
 	rules["rules"][1] = "segwit"
 	params[0] = rules
-	resp, status, err := r.doRequest("getblocktemplate", params)
-	if err != nil {
-		return json.RawMessage{}, err
+	if r.Algorithm != "" {
+		params = append(params, r.Algorithm)
 	}
 
-	if status != 200 {
-		return json.RawMessage{}, handleHttpError(resp, status)
-	}
+	var template json.RawMessage
+	err := r.Call(ctx, "getblocktemplate", params, &template)
+	return template, err
+}
 
-	return resp.Result, nil
+// https://github.com/bitcoin/bips/blob/master/bip-0023.mediawiki#block-proposal
+// Returns the node's rejection reason, empty when the block would be accepted (proof of work aside)
+func (r *RPCClient) ProposeBlock(ctx context.Context, blockHex string) (string, error) {
+	params := make([]interface{}, 1)
+	proposal := make(map[string]interface{})
+	proposal["mode"] = "proposal"
+	proposal["data"] = blockHex
+	proposal["rules"] = []string{"mweb", "segwit"}
+	params[0] = proposal
+
+	// null leaves the reason empty
+	var reason string
+	err := r.Call(ctx, "getblocktemplate", params, &reason)
+	return reason, err
 }
 
-func (r *RPCClient) CreateAuxBlock(rewardAddress string) (json.RawMessage, error) {
+func (r *RPCClient) CreateAuxBlock(ctx context.Context, rewardAddress string) (json.RawMessage, error) {
 	params := make([]any, 1)
 	params[0] = rewardAddress
-	resp, status, err := r.doRequest("createauxblock", params)
-	if err != nil {
-		return json.RawMessage{}, err
-	}
-	if status != 200 {
-		return json.RawMessage{}, handleHttpError(resp, status)
-	}
 
-	return resp.Result, nil
+	var auxBlock json.RawMessage
+	err := r.Call(ctx, "createauxblock", params, &auxBlock)
+	return auxBlock, err
 }
 
 type GetBlockReplyPart struct {
@@ -154,22 +222,16 @@ type GetBlockReply struct {
 	Transactions []string `json:"tx"`      // From Block Reply
 }
 
-func (r *RPCClient) GetLatestBlock() (GetBlockReplyPart, error) {
+func (r *RPCClient) GetLatestBlock(ctx context.Context) (GetBlockReplyPart, error) {
 	var reply GetBlockReplyPart
 
-	resp, status, err := r.doRequest("getbestblockhash", nil)
+	var blockHash string
+	err := r.Call(ctx, "getbestblockhash", nil, &blockHash)
 	if err != nil {
 		return reply, err
 	}
 
-	if status != 200 {
-		return reply, handleHttpError(resp, status)
-	}
-
-	var blockHash string
-	json.Unmarshal(resp.Result, &blockHash)
-
-	block, err := r.GetBlockByHash(blockHash)
+	block, err := r.GetBlockByHash(ctx, blockHash)
 	if err != nil {
 		return reply, err
 	}
@@ -180,86 +242,69 @@ func (r *RPCClient) GetLatestBlock() (GetBlockReplyPart, error) {
 	return reply, nil
 }
 
-func (r *RPCClient) GetBlockByHash(hash string) (*GetBlockReply, error) {
+func (r *RPCClient) GetBlockByHash(ctx context.Context, hash string) (*GetBlockReply, error) {
 	var reply GetBlockReply
 	params := make([]interface{}, 1)
 	params[0] = hash
-	resp, status, err := r.doRequest("getblock", params)
-
-	if err != nil {
-		return &reply, err
-	}
-
-	if status != 200 {
-		return &reply, handleHttpError(resp, status)
-	}
-
-	json.Unmarshal(resp.Result, &reply)
+	err := r.Call(ctx, "getblock", params, &reply)
+	return &reply, err
+}
 
-	return &reply, nil
+// The serialized block, with its MWEB extension block on Litecoin
+func (r *RPCClient) GetBlockHex(ctx context.Context, hash string) (string, error) {
+	var blockHex string
+	verbosity := 0
+	err := r.Call(ctx, "getblock", []interface{}{hash, verbosity}, &blockHex)
+	return blockHex, err
 }
 
-func (r *RPCClient) GetBlockByHeight(height int64) (*GetBlockReply, error) {
+func (r *RPCClient) GetBlockByHeight(ctx context.Context, height int64) (*GetBlockReply, error) {
 	var reply GetBlockReply
 	rpcParams := make([]interface{}, 1)
 	rpcParams[0] = height
-	resp, status, err := r.doRequest("getblockhash", rpcParams)
-	if err != nil {
-		return &reply, err
-	}
-
-	if status != 200 {
-		return &reply, handleHttpError(resp, status)
-	}
 
 	var blockHash string
-	json.Unmarshal(resp.Result, &blockHash)
-
-	block, err := r.GetBlockByHash(blockHash)
+	err := r.Call(ctx, "getblockhash", rpcParams, &blockHash)
 	if err != nil {
 		return &reply, err
 	}
 
-	return block, nil
+	return r.GetBlockByHash(ctx, blockHash)
 }
 
-func (r *RPCClient) SubmitBlock(submission []interface{}) (bool, error) {
+func (r *RPCClient) SubmitBlock(ctx context.Context, submission []interface{}) (bool, error) {
 	rpcParams := make([]interface{}, 1)
 
 	// This ultimately will be the point of inversion for each chain block...
 	// Each chain block will have it's own rpc.SubmitBlock.. well, all RPC methods really
 	rpcParams[0] = submission[0].(string)
 
-	resp, status, err := r.doRequest("submitblock", rpcParams)
+	resp, status, err := r.doRequest(ctx, "submitblock", rpcParams)
 	if err != nil {
 		return false, err
 	}
 
 	result := string(resp.Result)
 	if status != 200 || result != "null" {
-		m := "HTTP (%v) %v error-msg: %v"
-		m = fmt.Sprintf(m, status, result, resp.Error.Message)
-		return false, errors.New(m)
+		return false, submitError(resp, status)
 	}
 
 	return true, nil
 }
 
-func (r *RPCClient) SubmitAuxBlock(auxBlockHash string, primaryAuxPow string) (bool, error) {
+func (r *RPCClient) SubmitAuxBlock(ctx context.Context, auxBlockHash string, primaryAuxPow string) (bool, error) {
 	rpcParams := make([]any, 2)
 
 	rpcParams[0] = auxBlockHash
 	rpcParams[1] = primaryAuxPow
 
-	resp, status, err := r.doRequest("submitauxblock", rpcParams)
+	resp, status, err := r.doRequest(ctx, "submitauxblock", rpcParams)
 	if err != nil {
 		return false, err
 	}
 	result := string(resp.Result)
 	if status != 200 || result != "true" {
-		m := "HTTP (%v) %v error-msg: %v"
-		m = fmt.Sprintf(m, status, result, resp.Error.Message)
-		return false, errors.New(m)
+		return false, submitError(resp, status)
 	}
 
 	return true, nil
@@ -269,52 +314,59 @@ type validateAddressResponse struct {
 	ScriptPubKey string `json:"scriptPubKey"`
 }
 
-func (r *RPCClient) ValidateAddress(address string) (validateAddressResponse, error) {
+func (r *RPCClient) ValidateAddress(ctx context.Context, address string) (validateAddressResponse, error) {
 	var response validateAddressResponse
 
 	rpcParams := make([]interface{}, 1)
 	rpcParams[0] = address
 
-	resp, status, err := r.doRequest("validateaddress", rpcParams)
-	if err != nil {
-		return response, err
-	}
-	if status != 200 {
-		return response, errors.New("RPC: `validateaddress` failed HTTP 200")
-	}
-
-	err = json.Unmarshal(resp.Result, &response)
-	if err != nil {
-		return response, err
-	}
+	err := r.Call(ctx, "validateaddress", rpcParams, &response)
+	return response, err
+}
 
-	return response, nil
+// Checks a base64 signmessage signature, only legacy (P2PKH) addresses can sign messages
+func (r *RPCClient) VerifyMessage(ctx context.Context, address, signature, message string) (bool, error) {
+	var verified bool
+	err := r.Call(ctx, "verifymessage", []interface{}{address, signature, message}, &verified)
+	return verified, err
 }
 
 type blockChainInfoResponse struct {
-	Chain             string  `json:"chain"`
-	NetworkDifficulty float64 `json:"difficulty"`
+	Chain                string             `json:"chain"`
+	Blocks               int64              `json:"blocks"`
+	BestBlockHash        string             `json:"bestblockhash"`
+	InitialBlockDownload bool               `json:"initialblockdownload"`
+	NetworkDifficulty    float64            `json:"difficulty"`
+	Difficulties         map[string]float64 `json:"difficulties"`
 }
 
-func (r *RPCClient) GetBlockChainInfo() (blockChainInfoResponse, error) {
+func (r *RPCClient) GetBlockChainInfo(ctx context.Context) (blockChainInfoResponse, error) {
 	var response blockChainInfoResponse
 
-	resp, status, err := r.doRequest("getblockchaininfo", nil)
+	err := r.Call(ctx, "getblockchaininfo", nil, &response)
 	if err != nil {
 		return response, err
 	}
-	if status != 200 {
-		return response, errors.New("RPC: `getblockchaininfo` failed HTTP 200")
-	}
 
-	err = json.Unmarshal(resp.Result, &response)
-	if err != nil {
-		return response, err
+	if difficulty, ok := response.Difficulties[r.Algorithm]; ok {
+		response.NetworkDifficulty = difficulty
 	}
 
 	return response, nil
 }
 
+// submitblock and submitauxblock report rejections in the result, errors in the error
+func submitError(response rpcResponse, status int) error {
+	result := string(response.Result)
+	if response.Error == nil {
+		return fmt.Errorf("HTTP (%v) %v", status, result)
+	}
+	return fmt.Errorf("HTTP (%v) %v error-msg: %w", status, result, response.Error)
+}
+
 func handleHttpError(response rpcResponse, status int) error {
-	return errors.New("HTTP " + strconv.Itoa(status) + ": " + response.Error.Message)
+	if response.Error == nil {
+		return errors.New("HTTP " + strconv.Itoa(status))
+	}
+	return fmt.Errorf("HTTP %v: %w", status, response.Error)
 }
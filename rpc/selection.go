@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Bounds the tip queries of a selection, a node slower than this isn't worth a template
+const tipQueryTimeout = 2 * time.Second
+
+// The most up to date, lowest latency healthy node to fetch templates from.  The health loop
+// decides which nodes are healthy, their tips are queried now as its reports may predate the
+// latest block.  Falls back to the active node when no other node qualifies.
+func (m *Manager) GetBestClient(ctx context.Context) *RPCClient {
+	if len(m.clients) < 2 {
+		return m.GetActiveClient()
+	}
+
+	health := m.queryTips(ctx, m.Health())
+	majorityHeight := majorityTipHeight(health)
+
+	best := -1
+	for i, node := range health {
+		if !node.Healthy || node.Height < majorityHeight {
+			continue
+		}
+		if best < 0 || node.Height > health[best].Height ||
+			(node.Height == health[best].Height && node.Latency < health[best].Latency) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		log.Printf("No healthy %v node is synced to the majority tip, staying on the active node", m.chainName)
+		return m.GetActiveClient()
+	}
+	return m.clients[best]
+}
+
+// Replaces the healthy nodes' heights, tips and latencies with their current ones, concurrently.
+// A node that doesn't answer in time is left out of this selection.
+func (m *Manager) queryTips(ctx context.Context, health []NodeHealth) []NodeHealth {
+	ctx, cancel := context.WithTimeout(ctx, tipQueryTimeout)
+	defer cancel()
+
+	var wait sync.WaitGroup
+	for i := range health {
+		if !health[i].Healthy {
+			continue
+		}
+		wait.Add(1)
+		go func(node *NodeHealth, client *RPCClient) {
+			defer wait.Done()
+			var info healthBlockChainInfo
+			start := time.Now()
+			err := client.Call(ctx, "getblockchaininfo", nil, &info)
+			if err != nil {
+				node.Healthy = false
+				return
+			}
+			node.Latency = time.Since(start)
+			node.Height = info.Blocks
+			node.BestBlockHash = info.BestBlockHash
+		}(&health[i], m.clients[i])
+	}
+	wait.Wait()
+
+	return health
+}
+
+// The height of the tip most healthy nodes agree on, ties go to the higher tip
+func majorityTipHeight(health []NodeHealth) int64 {
+	votes := make(map[string]int)
+	heights := make(map[string]int64)
+	for _, node := range health {
+		if !node.Healthy {
+			continue
+		}
+		votes[node.BestBlockHash]++
+		heights[node.BestBlockHash] = node.Height
+	}
+
+	var majorityHeight int64
+	mostVotes := 0
+	for hash, count := range votes {
+		if count > mostVotes || (count == mostVotes && heights[hash] > majorityHeight) {
+			mostVotes = count
+			majorityHeight = heights[hash]
+		}
+	}
+	return majorityHeight
+}
@@ -1,7 +1,7 @@
 package rpc
 
 import (
-	"encoding/json"
+	"context"
 	"time"
 )
 
@@ -14,96 +14,102 @@ type TransactionDetails struct {
 type Transaction struct {
 	TransactionID   string               `json:"txid"`
 	Amount          float64              `json:"amount"`
-	Confirmations   uint                 `json:"confirmations"`
+	Confirmations   int64                `json:"confirmations"` // Negative when conflicted
 	Blockhash       string               `json:"blockhash"`
 	Blockheight     uint                 `json:"blockheight"`
 	BlockTime       int64                `json:"blocktime"`
 	TransactionTime int64                `json:"time"`
 	RecievedTime    int64                `json:"recievedtime"`
 	Details         []TransactionDetails `json:"details"`
+	ReplacedBy      string               `json:"replaced_by_txid"`
+	Hex             string               `json:"hex"`
 }
 
-func (r *RPCClient) GetTransaction(transactionID string) (Transaction, error) {
+func (r *RPCClient) GetTransaction(ctx context.Context, transactionID string) (Transaction, error) {
 	params := make([]any, 1)
 	params[0] = transactionID
 
 	transaction := Transaction{}
+	err := r.Call(ctx, "gettransaction", params, &transaction)
+	return transaction, err
+}
 
-	resp, status, err := r.doRequest("gettransaction", params)
-	if err != nil {
-		return transaction, err
-	}
-	if status != 200 {
-		return transaction, handleHttpError(resp, status)
-	}
-
-	err = json.Unmarshal(resp.Result, &transaction)
-
+// Any transaction in the node's mempool, confirmed ones only with -txindex. Confirmations
+// is 0 while in the mempool.
+func (r *RPCClient) GetRawTransaction(ctx context.Context, transactionID string) (Transaction, error) {
+	transaction := Transaction{}
+	verbose := true
+	err := r.Call(ctx, "getrawtransaction", []interface{}{transactionID, verbose}, &transaction)
 	return transaction, err
 }
 
-func (r *RPCClient) SendMany(transactions map[string]float64) (string, error) {
-	params := make([]any, 2)
+// The comment is stored with the wallet transaction, see FindSentTransaction
+func (r *RPCClient) SendMany(ctx context.Context, transactions map[string]float64, comment string, fees FeeOptions) (string, error) {
 	from := ""
-	params[0] = from
-	params[1] = transactions
-
-	transactionID := ""
-
-	response, status, err := r.doRequest("sendmany", params)
-	if err != nil {
-		return transactionID, err
+	minimumConfirmations := 1
+	params := []interface{}{from, transactions, minimumConfirmations, comment, fees.subtractFeeFrom(transactions)}
+	if fees.FeeRate > 0 {
+		// replaceable and conf_target are left to the wallet
+		params = append(params, nil, nil, "unset", fees.FeeRate)
 	}
-	if status != 200 {
-		return transactionID, handleHttpError(response, status)
-	}
-
-	err = json.Unmarshal(response.Result, &transactionID)
 
+	transactionID := ""
+	err := r.Call(ctx, "sendmany", params, &transactionID)
 	return transactionID, err
 }
 
-func (r *RPCClient) GetWalletBalance() (float64, error) {
-	resp, status, err := r.doRequest("getbalance", nil)
-	if err != nil {
-		return 0, err
-	}
+type walletTransaction struct {
+	TransactionID string `json:"txid"`
+	Category      string `json:"category"`
+	Comment       string `json:"comment"`
+	Time          int64  `json:"time"`
+}
 
-	if status != 200 {
-		return 0, handleHttpError(resp, status)
+// Looks through the wallet's sends, newest first, back to since
+func (r *RPCClient) FindSentTransaction(ctx context.Context, comment string, since time.Time) (string, bool, error) {
+	const pageSize = 1000
+	for skip := 0; ; skip += pageSize {
+		var page []walletTransaction
+		err := r.Call(ctx, "listtransactions", []interface{}{"*", pageSize, skip}, &page)
+		if err != nil {
+			return "", false, err
+		}
+
+		oldest := time.Now().Unix()
+		for _, transaction := range page {
+			if transaction.Category == "send" && transaction.Comment == comment {
+				return transaction.TransactionID, true, nil
+			}
+			if transaction.Time < oldest {
+				oldest = transaction.Time
+			}
+		}
+
+		if len(page) < pageSize || oldest < since.Unix() {
+			return "", false, nil
+		}
 	}
-
-	var balance float64
-	json.Unmarshal(resp.Result, &balance)
-
-	return balance, nil
 }
 
-func (r *RPCClient) isWalletUnlocked() (bool, error) {
-	return false, nil
+// Lets the wallet forget an unconfirmed transaction that left the mempool so its inputs can be spent again
+func (r *RPCClient) AbandonTransaction(ctx context.Context, transactionID string) error {
+	return r.Call(ctx, "abandontransaction", []interface{}{transactionID}, nil)
 }
 
-func (r *RPCClient) lockWallet() error {
-	return nil
+func (r *RPCClient) GetWalletBalance(ctx context.Context) (float64, error) {
+	var balance float64
+	err := r.Call(ctx, "getbalance", nil, &balance)
+	return balance, err
 }
 
-func (r *RPCClient) SendTransaction(to string, value float64) (string, error) {
+func (r *RPCClient) SendTransaction(ctx context.Context, to string, value float64) (string, error) {
 	rpcParams := make([]interface{}, 2)
 	rpcParams[0] = to
 	rpcParams[1] = value
-	resp, status, err := r.doRequest("sendtoaddress", rpcParams)
-	if err != nil {
-		return "", err
-	}
-
-	if status != 200 {
-		return "", handleHttpError(resp, status)
-	}
 
 	var receiptHash string
-	json.Unmarshal(resp.Result, &receiptHash)
-
-	return receiptHash, nil
+	err := r.Call(ctx, "sendtoaddress", rpcParams, &receiptHash)
+	return receiptHash, err
 }
 
 type Tx struct {
@@ -112,12 +118,12 @@ type Tx struct {
 }
 
 type TxReceipt struct {
-	BlockHeight    uint64    `json:""`
-	BlockHash      string    `json:"blockhash"`
-	BlockTime      time.Time `json:"blocktime"`
-	Fee            float32   `json:"fee"`
-	ConfirmedCount int64     `json:"confirmations"`
-	TxId           string    `json:"txid"`
+	BlockHeight    uint64  `json:""`
+	BlockHash      string  `json:"blockhash"`
+	BlockTime      int64   `json:"blocktime"` // Unix seconds
+	Fee            float32 `json:"fee"`
+	ConfirmedCount int64   `json:"confirmations"`
+	TxId           string  `json:"txid"`
 }
 
 func (r *TxReceipt) Confirmed() bool {
@@ -128,27 +134,16 @@ func (r *TxReceipt) Successful() bool {
 	return r.Confirmed()
 }
 
-func (r *RPCClient) GetTxReceipt(txId string) (*TxReceipt, error) {
+func (r *RPCClient) GetTxReceipt(ctx context.Context, txId string) (*TxReceipt, error) {
 	var rcpt TxReceipt
 	rpcParams := make([]interface{}, 1)
 	rpcParams[0] = txId
-	resp, status, err := r.doRequest("gettransaction", rpcParams)
+	err := r.Call(ctx, "gettransaction", rpcParams, &rcpt)
 	if err != nil {
 		return &rcpt, err
 	}
 
-	if status != 200 {
-		return &rcpt, handleHttpError(resp, status)
-	}
-
-	respStr := ""
-	for _, s := range resp.Result {
-		respStr = respStr + string(s)
-	}
-
-	json.Unmarshal(resp.Result, &rcpt)
-
-	block, err := r.GetBlockByHash(rcpt.BlockHash)
+	block, err := r.GetBlockByHash(ctx, rcpt.BlockHash)
 	if err != nil {
 		return &rcpt, err
 	}
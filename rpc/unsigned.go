@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+)
+
+// Funded from the wallet but left unsigned, for wallets holding only watch-only keys.
+// The inputs are locked so later batches can't spend them too, see UnlockInputs.
+func (r *RPCClient) CreateFundedPSBT(ctx context.Context, outputs map[string]float64, fees FeeOptions) (string, error) {
+	var response struct {
+		PSBT string `json:"psbt"`
+	}
+	options := map[string]interface{}{
+		"subtractFeeFromOutputs": fees.subtractFeeFromOutputs(outputs),
+		"lockUnspents":           true,
+	}
+	if fees.FeeRate > 0 {
+		options["fee_rate"] = fees.FeeRate
+	}
+	locktime := 0
+	params := []interface{}{[]interface{}{}, outputs, locktime, options}
+	err := r.Call(ctx, "walletcreatefundedpsbt", params, &response)
+	return response.PSBT, err
+}
+
+// For wallets without PSBT support, inputs are locked as for CreateFundedPSBT
+func (r *RPCClient) CreateFundedRawTransaction(ctx context.Context, outputs map[string]float64, fees FeeOptions) (string, error) {
+	var unfunded string
+	err := r.Call(ctx, "createrawtransaction", []interface{}{[]interface{}{}, outputs}, &unfunded)
+	if err != nil {
+		return "", err
+	}
+
+	var funded struct {
+		Hex string `json:"hex"`
+	}
+	options := map[string]interface{}{
+		"subtractFeeFromOutputs": fees.subtractFeeFromOutputs(outputs),
+		"lockUnspents":           true,
+		"includeWatching":        true, // Older wallets only spend watch-only coins when asked
+	}
+	if fees.FeeRate > 0 {
+		options["feeRate"] = fees.coinsPerKB()
+	}
+	err = r.Call(ctx, "fundrawtransaction", []interface{}{unfunded, options}, &funded)
+	return funded.Hex, err
+}
+
+func (r *RPCClient) FinalizePSBT(ctx context.Context, psbt string) (string, error) {
+	var response struct {
+		Hex      string `json:"hex"`
+		Complete bool   `json:"complete"`
+	}
+	err := r.Call(ctx, "finalizepsbt", []interface{}{psbt}, &response)
+	if err != nil {
+		return "", err
+	}
+	if !response.Complete {
+		return "", errors.New("PSBT is not fully signed")
+	}
+	return response.Hex, nil
+}
+
+type DecodedOutput struct {
+	Value        float64 `json:"value"`
+	ScriptPubKey struct {
+		Address   string   `json:"address"`
+		Addresses []string `json:"addresses"` // Before Bitcoin Core 22
+	} `json:"scriptPubKey"`
+}
+
+func (o DecodedOutput) Address() string {
+	if o.ScriptPubKey.Address != "" {
+		return o.ScriptPubKey.Address
+	}
+	if len(o.ScriptPubKey.Addresses) == 1 {
+		return o.ScriptPubKey.Addresses[0]
+	}
+	return ""
+}
+
+type DecodedInput struct {
+	TransactionID string `json:"txid"`
+	Output        uint32 `json:"vout"`
+}
+
+type DecodedTransaction struct {
+	TransactionID string          `json:"txid"`
+	Inputs        []DecodedInput  `json:"vin"`
+	Outputs       []DecodedOutput `json:"vout"`
+}
+
+func (r *RPCClient) DecodeRawTransaction(ctx context.Context, transactionHex string) (DecodedTransaction, error) {
+	var decoded DecodedTransaction
+	err := r.Call(ctx, "decoderawtransaction", []interface{}{transactionHex}, &decoded)
+	return decoded, err
+}
+
+func (r *RPCClient) DecodePSBT(ctx context.Context, psbt string) (DecodedTransaction, error) {
+	var decoded struct {
+		Transaction DecodedTransaction `json:"tx"`
+	}
+	err := r.Call(ctx, "decodepsbt", []interface{}{psbt}, &decoded)
+	return decoded.Transaction, err
+}
+
+func (r *RPCClient) SendRawTransaction(ctx context.Context, transactionHex string) (string, error) {
+	var transactionID string
+	err := r.Call(ctx, "sendrawtransaction", []interface{}{transactionHex}, &transactionID)
+	return transactionID, err
+}
+
+// Gives inputs locked by funding back to the wallet. Locks only last until the node restarts,
+// after which unlocking fails as the outputs aren't locked anymore.
+func (r *RPCClient) UnlockInputs(ctx context.Context, inputs []DecodedInput) error {
+	if len(inputs) < 1 {
+		return nil
+	}
+	unlock := true
+	return r.Call(ctx, "lockunspent", []interface{}{unlock, inputs}, nil)
+}
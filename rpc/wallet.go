@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+var ErrWalletPassphraseMissing = errors.New("wallet is encrypted and locked but no passphrase is configured")
+
+type walletInfo struct {
+	// Only present for encrypted wallets, 0 while locked
+	UnlockedUntil *int64 `json:"unlocked_until"`
+}
+
+func (r *RPCClient) walletLockState(ctx context.Context) (encrypted bool, unlocked bool, err error) {
+	var info walletInfo
+	err = r.Call(ctx, "getwalletinfo", nil, &info)
+	if err != nil {
+		return false, false, err
+	}
+	if info.UnlockedUntil == nil {
+		return false, true, nil
+	}
+	return true, *info.UnlockedUntil > time.Now().Unix(), nil
+}
+
+func (r *RPCClient) isWalletUnlocked(ctx context.Context) (bool, error) {
+	_, unlocked, err := r.walletLockState(ctx)
+	return unlocked, err
+}
+
+func (r *RPCClient) unlockWallet(ctx context.Context, passphrase string, window time.Duration) error {
+	seconds := int64(window.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return r.Call(ctx, "walletpassphrase", []interface{}{passphrase, seconds}, nil)
+}
+
+func (r *RPCClient) lockWallet(ctx context.Context) error {
+	return r.Call(ctx, "walletlock", nil, nil)
+}
+
+// Runs send with the wallet unlocked for at most window, relocking straight after.
+// The passphrase is only fetched when the wallet is actually locked, and nothing is
+// sent when it can't be unlocked. A wallet someone else unlocked is left as it was.
+func (r *RPCClient) WithUnlockedWallet(ctx context.Context, passphrase func() (string, error), window time.Duration, send func() error) error {
+	encrypted, unlocked, err := r.walletLockState(ctx)
+	if err != nil {
+		return err
+	}
+	if !encrypted || unlocked {
+		return send()
+	}
+
+	secret, err := passphrase()
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return ErrWalletPassphraseMissing
+	}
+
+	err = r.unlockWallet(ctx, secret, window)
+	if err != nil {
+		return errors.Join(errors.New("failed to unlock wallet"), err)
+	}
+	defer func() {
+		// Relocked even when the payout was cancelled
+		err := r.lockWallet(context.WithoutCancel(ctx))
+		if err != nil {
+			log.Printf("⚠️  Failed to relock %v wallet: %v", r.Name, err)
+		}
+	}()
+
+	return send()
+}